@@ -0,0 +1,197 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"runtime"
+	rtmetrics "runtime/metrics"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"heap_cost_analyzer/internal/analyzer"
+)
+
+const (
+	maxBatchBodyBytes = 8 << 20
+	maxBatchIDs       = 1000000
+	defaultBatchN     = 10000
+)
+
+// batchMu serializes batch measurements; each one forces a GC and reads
+// process-wide counters that concurrent batches would pollute.
+var batchMu sync.Mutex
+
+// batchReport describes the cost of building one batch and keeping it live
+// across a GC cycle.
+type batchReport struct {
+	Mode          string                 `json:"mode"`
+	Count         int                    `json:"count"`
+	BuildNs       int64                  `json:"build_ns"`
+	AllocBytes    uint64                 `json:"alloc_bytes"`
+	AllocObjects  uint64                 `json:"alloc_objects"`
+	GCNs          int64                  `json:"gc_ns"`
+	ScanHeapBytes uint64                 `json:"scan_heap_bytes"`
+	First         *analyzer.RequestStats `json:"first,omitempty"`
+}
+
+type batchCompareJSON struct {
+	Values   batchReport `json:"values"`
+	Pointers batchReport `json:"pointers"`
+	// Ratios are pointers/values; above 1 means the pointer slice costs more.
+	BuildRatio    float64 `json:"build_ratio"`
+	GCRatio       float64 `json:"gc_ratio"`
+	ScanHeapDelta int64   `json:"scan_heap_delta_bytes"`
+	ObjectsDelta  int64   `json:"alloc_objects_delta"`
+}
+
+// measureBatch builds a batch in the given mode ("values" or "pointers"),
+// then forces a GC while the batch is still live. GC wall time and the
+// scannable heap size after that cycle show how much marking work the
+// batch's shape adds.
+func measureBatch(mode string, ids []uint64) batchReport {
+	batchMu.Lock()
+	defer batchMu.Unlock()
+
+	runtime.GC()
+	samples := []rtmetrics.Sample{
+		{Name: "/gc/heap/allocs:bytes"},
+		{Name: "/gc/heap/allocs:objects"},
+		{Name: "/gc/scan/heap:bytes"},
+	}
+	rtmetrics.Read(samples)
+	allocBytes, allocObjects := samples[0].Value.Uint64(), samples[1].Value.Uint64()
+
+	rep := batchReport{Mode: mode, Count: len(ids)}
+	var values []analyzer.RequestStats
+	var pointers []*analyzer.RequestStats
+	start := time.Now()
+	if mode == "pointers" {
+		pointers = analyzer.ProcessBatchPointers(ids)
+	} else {
+		values = analyzer.ProcessBatchValues(ids)
+	}
+	rep.BuildNs = time.Since(start).Nanoseconds()
+
+	rtmetrics.Read(samples)
+	rep.AllocBytes = samples[0].Value.Uint64() - allocBytes
+	rep.AllocObjects = samples[1].Value.Uint64() - allocObjects
+
+	gcStart := time.Now()
+	runtime.GC()
+	rep.GCNs = time.Since(gcStart).Nanoseconds()
+	rtmetrics.Read(samples)
+	rep.ScanHeapBytes = samples[2].Value.Uint64()
+
+	if len(pointers) > 0 {
+		first := *pointers[0]
+		rep.First = &first
+	} else if len(values) > 0 {
+		first := values[0]
+		rep.First = &first
+	}
+	runtime.KeepAlive(values)
+	runtime.KeepAlive(pointers)
+	return rep
+}
+
+// parseBatchIDs reads IDs from a JSON array or a newline-delimited body.
+// GET requests may instead pass ?n= to use IDs 0..n-1.
+func parseBatchIDs(w http.ResponseWriter, r *http.Request) ([]uint64, error) {
+	if r.Method == http.MethodGet {
+		n, err := intParam(r.URL.Query().Get("n"), defaultBatchN)
+		if err != nil || n <= 0 || n > maxBatchIDs {
+			return nil, fmt.Errorf("invalid 'n' parameter (1-%d)", maxBatchIDs)
+		}
+		ids := make([]uint64, n)
+		for i := range ids {
+			ids[i] = uint64(i)
+		}
+		return ids, nil
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBatchBodyBytes))
+	if err != nil {
+		return nil, fmt.Errorf("read body: %w", err)
+	}
+	var ids []uint64
+	if trimmed := bytes.TrimSpace(body); len(trimmed) > 0 && trimmed[0] == '[' {
+		if err := json.Unmarshal(trimmed, &ids); err != nil {
+			return nil, fmt.Errorf("invalid JSON array: %w", err)
+		}
+	} else {
+		sc := bufio.NewScanner(bytes.NewReader(body))
+		for line := 1; sc.Scan(); line++ {
+			s := strings.TrimSpace(sc.Text())
+			if s == "" {
+				continue
+			}
+			id, err := strconv.ParseUint(s, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: invalid ID %q", line, s)
+			}
+			ids = append(ids, id)
+		}
+		if err := sc.Err(); err != nil {
+			return nil, err
+		}
+	}
+	if len(ids) == 0 {
+		return nil, fmt.Errorf("no IDs in request body")
+	}
+	if len(ids) > maxBatchIDs {
+		return nil, fmt.Errorf("too many IDs (max %d)", maxBatchIDs)
+	}
+	return ids, nil
+}
+
+// batchHandler processes a batch using one mode and reports its cost.
+func batchHandler(mode, endpoint string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		ids, err := parseBatchIDs(w, r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		rep := measureBatch(mode, ids)
+		requestMetrics.Observe(endpoint, time.Since(start))
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(rep)
+	}
+}
+
+// batchCompareHandler runs the same IDs through both modes and reports the differences.
+func batchCompareHandler(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	ids, err := parseBatchIDs(w, r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	values := measureBatch("values", ids)
+	pointers := measureBatch("pointers", ids)
+	out := batchCompareJSON{
+		Values:        values,
+		Pointers:      pointers,
+		BuildRatio:    ratio(pointers.BuildNs, values.BuildNs),
+		GCRatio:       ratio(pointers.GCNs, values.GCNs),
+		ScanHeapDelta: int64(pointers.ScanHeapBytes) - int64(values.ScanHeapBytes),
+		ObjectsDelta:  int64(pointers.AllocObjects) - int64(values.AllocObjects),
+	}
+	requestMetrics.Observe("batch-compare", time.Since(start))
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(out)
+}
+
+func ratio(a, b int64) float64 {
+	if b == 0 {
+		return 0
+	}
+	return float64(a) / float64(b)
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+
+	"heap_cost_analyzer/internal/analyzer"
+	"lessonkit/dashboard"
+)
+
+var dash = newDashboard()
+
+func newDashboard() *dashboard.Dashboard {
+	d := dashboard.New("Heap Cost Analyzer Dashboard", dashboard.Dark)
+	d.Nav = []dashboard.Link{
+		{Label: "Dashboard", URL: "/"},
+		{Label: "Demo Heap", URL: "/stats-heap?id=1"},
+		{Label: "Demo Stack", URL: "/stats-stack?id=2"},
+		{Label: "Demo Pooled", URL: "/stats-pooled?id=3"},
+		{Label: "Demo Into", URL: "/stats-into?id=4"},
+		{Label: "Run Experiment", URL: "/experiment?concurrency=8&duration=2s"},
+		{Label: "Batch Compare (100k)", URL: "/batch-compare?n=100000"},
+		{Label: "JSON Metrics", URL: "/api/metrics"},
+		{Label: "Prometheus", URL: "/metrics"},
+		{Label: "JSON History", URL: "/api/history?last=1m"},
+		{Label: "JSON Layout", URL: "/api/layout"},
+	}
+	d.Add(dashboard.Panel{ID: "requests", Render: requestsPanel})
+	d.Add(dashboard.Panel{ID: "history", Title: fmt.Sprintf("History (last %ds)", historyDashboard), Render: historyPanel})
+	d.Add(dashboard.Panel{ID: "profile-actions", Title: "Heap profile diff", Static: true, Render: profileActions})
+	d.Add(dashboard.Panel{ID: "profile", Render: profilePanel})
+	d.Add(dashboard.Panel{ID: "layout", Title: "Struct layout", Static: true, Render: layoutPanel})
+	return d
+}
+
+func dashboardHandler(w http.ResponseWriter, r *http.Request) {
+	dash.ServeHTTP(w, r)
+}
+
+// requestsPanel shows per-strategy request counts and the last stats each produced.
+func requestsPanel() []dashboard.Block {
+	metricsMu.RLock()
+	heapCnt, stackCnt := heapRequestCount, stackRequestCount
+	pooledCnt, intoCnt := pooledRequestCount, intoRequestCount
+	lastHeap := lastHeapStats
+	lastStack, lastPooled, lastInto := lastStackStats, lastPooledStats, lastIntoStats
+	metricsMu.RUnlock()
+
+	var heap analyzer.RequestStats
+	if lastHeap != nil {
+		heap = *lastHeap
+	}
+	return []dashboard.Block{dashboard.Cards{
+		{Label: "Heap requests", Value: heapCnt},
+		{Label: "Stack requests", Value: stackCnt},
+		{Label: "Pooled requests", Value: pooledCnt},
+		{Label: "Into requests", Value: intoCnt},
+		{Label: "Last heap stats", Value: formatStats(heap)},
+		{Label: "Last stack stats", Value: formatStats(lastStack)},
+		{Label: "Last pooled stats", Value: formatStats(lastPooled)},
+		{Label: "Last into stats", Value: formatStats(lastInto)},
+	}}
+}
+
+func formatStats(s analyzer.RequestStats) string {
+	return fmt.Sprintf("ID=%d Timestamp=%d Duration=%s Status=%d", s.ID, s.Timestamp, s.Duration, s.Status)
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"heap_cost_analyzer/internal/analyzer"
+	"heap_cost_analyzer/internal/loadtest"
+	"lessonkit/dashboard"
+)
+
+const (
+	defaultExperimentConcurrency = 8
+	maxExperimentConcurrency     = 256
+	defaultExperimentDuration    = 2 * time.Second
+	// Each scenario runs for the full duration, so keep the total under writeTimeout.
+	maxExperimentDuration = 20 * time.Second
+)
+
+// pointerSlot and valueSlot give each worker its own cache line to write to,
+// keeping results live without false sharing skewing throughput.
+type pointerSlot struct {
+	p *analyzer.RequestStats
+	_ [56]byte
+}
+
+type valueSlot struct {
+	v analyzer.RequestStats
+	_ [32]byte
+}
+
+// experimentScenarios are driven side by side by /experiment.
+var experimentScenarios = []loadtest.Scenario{
+	{Name: "pointer", NewOp: func(workers int) func(int, uint64) {
+		sinks := make([]pointerSlot, workers)
+		return func(w int, i uint64) { sinks[w].p = analyzer.ProcessRequestPointer(i) }
+	}},
+	{Name: "value", NewOp: func(workers int) func(int, uint64) {
+		sinks := make([]valueSlot, workers)
+		return func(w int, i uint64) { sinks[w].v = analyzer.ProcessRequestValue(i) }
+	}},
+	{Name: "pooled", NewOp: func(workers int) func(int, uint64) {
+		sinks := make([]valueSlot, workers)
+		return func(w int, i uint64) {
+			stats := analyzer.ProcessRequestPooled(i)
+			sinks[w].v = *stats
+			stats.Release()
+		}
+	}},
+	{Name: "into", NewOp: func(workers int) func(int, uint64) {
+		sinks := make([]valueSlot, workers)
+		return func(w int, i uint64) { analyzer.ProcessRequestInto(i, &sinks[w].v) }
+	}},
+}
+
+// experimentMu serializes experiments; concurrent runs would share GC stats.
+var experimentMu sync.Mutex
+
+type experimentJSON struct {
+	Concurrency int               `json:"concurrency"`
+	DurationNs  int64             `json:"duration_ns"`
+	Results     []loadtest.Result `json:"results"`
+}
+
+// experimentHandler drives every experiment scenario at the requested
+// concurrency and duration and renders the results side by side.
+// Query params: concurrency, duration (Go duration), format=json.
+func experimentHandler(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	concurrency, err := intParam(q.Get("concurrency"), defaultExperimentConcurrency)
+	if err != nil || concurrency <= 0 || concurrency > maxExperimentConcurrency {
+		http.Error(w, fmt.Sprintf("invalid 'concurrency' parameter (1-%d)", maxExperimentConcurrency), http.StatusBadRequest)
+		return
+	}
+	duration := defaultExperimentDuration
+	if s := q.Get("duration"); s != "" {
+		duration, err = time.ParseDuration(s)
+		if err != nil || duration <= 0 || duration*time.Duration(len(experimentScenarios)) > maxExperimentDuration {
+			http.Error(w, fmt.Sprintf("invalid 'duration' parameter (total run must be <= %s)", maxExperimentDuration), http.StatusBadRequest)
+			return
+		}
+	}
+
+	cfg := loadtest.Config{Concurrency: concurrency, Duration: duration}
+	experimentMu.Lock()
+	results, err := loadtest.Compare(cfg, experimentScenarios...)
+	experimentMu.Unlock()
+	if err != nil {
+		http.Error(w, fmt.Sprintf("experiment error: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	if q.Get("format") == "json" {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(experimentJSON{Concurrency: concurrency, DurationNs: duration.Nanoseconds(), Results: results})
+		return
+	}
+	experimentPage(cfg, results).ServeHTTP(w, r)
+}
+
+// experimentPage renders results as a one-off dashboard page with one
+// column per scenario.
+func experimentPage(cfg loadtest.Config, results []loadtest.Result) *dashboard.Dashboard {
+	d := dashboard.New("Request Stats Strategy Experiment", dashboard.Dark)
+	d.StreamPath = ""
+	d.Intro = fmt.Sprintf("Concurrency %d, %s per scenario.", cfg.Concurrency, cfg.Duration)
+	d.Nav = []dashboard.Link{
+		{Label: "Dashboard", URL: "/"},
+		{Label: "JSON", URL: fmt.Sprintf("/experiment?concurrency=%d&duration=%s&format=json", cfg.Concurrency, cfg.Duration)},
+	}
+
+	rows := []struct {
+		label string
+		cell  func(loadtest.Result) any
+	}{
+		{"Ops", func(r loadtest.Result) any { return r.Ops }},
+		{"Throughput (ops/s)", func(r loadtest.Result) any { return fmt.Sprintf("%.0f", r.OpsPerSec) }},
+		{"Bytes/op", func(r loadtest.Result) any { return fmt.Sprintf("%.1f", r.BytesPerOp) }},
+		{"Allocs/op", func(r loadtest.Result) any { return fmt.Sprintf("%.2f", r.AllocsPerOp) }},
+		{"Alloc rate (B/s)", func(r loadtest.Result) any { return fmt.Sprintf("%.0f", r.AllocBytesPerSec) }},
+		{"GC cycles", func(r loadtest.Result) any { return r.NumGC }},
+		{"GC pause total", func(r loadtest.Result) any { return r.PauseTotal }},
+		{"GC pause max", func(r loadtest.Result) any { return r.MaxPause }},
+	}
+	table := &dashboard.Table{Columns: []string{"Metric"}}
+	for _, r := range results {
+		table.Columns = append(table.Columns, r.Name)
+	}
+	for _, row := range rows {
+		cells := []any{row.label}
+		for _, r := range results {
+			cells = append(cells, row.cell(r))
+		}
+		table.Rows = append(table.Rows, cells)
+	}
+	d.Add(dashboard.Panel{ID: "results", Title: "Results", Render: func() []dashboard.Block { return []dashboard.Block{table} }})
+	return d
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"heap_cost_analyzer/internal/timeseries"
+	"lessonkit/dashboard"
+)
+
+const (
+	historyInterval  = time.Second
+	historyCapacity  = 300 // 5 minutes at one point per second
+	historyDashboard = 60  // points shown per sparkline
+)
+
+// history keeps per-endpoint request and runtime time series for the dashboard.
+var history = timeseries.NewRecorder(historyInterval, historyCapacity, "heap", "stack", "pooled", "into")
+
+type historyJSON struct {
+	Endpoint   string             `json:"endpoint"`
+	IntervalNs int64              `json:"interval_ns"`
+	Points     []timeseries.Point `json:"points"`
+}
+
+// historyJSONHandler serves a range query over the recorded series.
+// Query params: endpoint (default all), from/to as unix seconds, or last as a duration.
+func historyJSONHandler(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	var from, to time.Time
+	if s := q.Get("last"); s != "" {
+		d, err := time.ParseDuration(s)
+		if err != nil || d <= 0 {
+			http.Error(w, "invalid 'last' parameter", http.StatusBadRequest)
+			return
+		}
+		from = time.Now().Add(-d)
+	}
+	for _, p := range []struct {
+		name string
+		dst  *time.Time
+	}{{"from", &from}, {"to", &to}} {
+		s := q.Get(p.name)
+		if s == "" {
+			continue
+		}
+		sec, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("invalid '%s' parameter", p.name), http.StatusBadRequest)
+			return
+		}
+		*p.dst = time.Unix(sec, 0)
+	}
+
+	endpoints := history.Endpoints()
+	if e := q.Get("endpoint"); e != "" {
+		endpoints = []string{e}
+	}
+	out := make([]historyJSON, 0, len(endpoints))
+	for _, e := range endpoints {
+		pts, ok := history.Range(e, from, to)
+		if !ok {
+			http.Error(w, fmt.Sprintf("unknown endpoint %q", e), http.StatusNotFound)
+			return
+		}
+		out = append(out, historyJSON{Endpoint: e, IntervalNs: history.Interval().Nanoseconds(), Points: pts})
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(out)
+}
+
+// historyPanel renders sparklines for the most recent points.
+func historyPanel() []dashboard.Block {
+	table := &dashboard.Table{Columns: []string{"Endpoint", "Req/s", "", "p50", "", "p99", ""}}
+	var runtimePts []timeseries.Point
+	for _, e := range history.Endpoints() {
+		pts := history.Last(e, historyDashboard)
+		runtimePts = pts
+		rate, p50, p99 := make([]float64, len(pts)), make([]float64, len(pts)), make([]float64, len(pts))
+		for i, p := range pts {
+			rate[i], p50[i], p99[i] = p.RequestRate, float64(p.P50), float64(p.P99)
+		}
+		table.Rows = append(table.Rows, []any{e,
+			sparkline(rate, "#0f4"), fmt.Sprintf("%.1f", lastValue(rate)),
+			sparkline(p50, "#4cf"), time.Duration(lastValue(p50)),
+			sparkline(p99, "#f84"), time.Duration(lastValue(p99))})
+	}
+
+	alloc, gcs := make([]float64, len(runtimePts)), make([]float64, len(runtimePts))
+	for i, p := range runtimePts {
+		alloc[i], gcs[i] = p.AllocBytesPerSec, float64(p.NumGC)
+	}
+	runtimeTable := &dashboard.Table{
+		Columns: []string{"Heap alloc rate", "", "GC cycles", ""},
+		Rows: [][]any{{
+			sparkline(alloc, "#0f4"), fmt.Sprintf("%.0f B/s", lastValue(alloc)),
+			sparkline(gcs, "#f84"), fmt.Sprintf("%.0f/interval", lastValue(gcs)),
+		}},
+	}
+	return []dashboard.Block{table, runtimeTable}
+}
+
+func sparkline(values []float64, color string) dashboard.Chart {
+	return dashboard.Chart{Values: values, Width: 120, Height: 24, Color: color}
+}
+
+func lastValue(v []float64) float64 {
+	if len(v) == 0 {
+		return 0
+	}
+	return v[len(v)-1]
+}
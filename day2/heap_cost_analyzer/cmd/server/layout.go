@@ -0,0 +1,62 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"heap_cost_analyzer/internal/analyzer"
+	"lessonkit/dashboard"
+	"lessonkit/layout"
+	"memory_arena_lesson/arena"
+)
+
+// structLayouts are the types whose size and padding the lesson explains.
+var structLayouts = layout.NewRegistry()
+
+// init panics if a type cannot be registered: that is a programming error,
+// and skipping it would silently drop the type from the page.
+func init() {
+	for _, t := range []struct {
+		name string
+		v    any
+	}{
+		{"analyzer.RequestStats", analyzer.RequestStats{}},
+		{"arena.MyData", arena.MyData{}},
+	} {
+		if err := structLayouts.Register(t.name, t.v); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// layoutJSONHandler reports field offsets, padding and the optimal ordering
+// for every registered struct, or just ?type=name.
+func layoutJSONHandler(w http.ResponseWriter, r *http.Request) {
+	reports := structLayouts.Reports()
+	if name := r.URL.Query().Get("type"); name != "" {
+		rep, ok := structLayouts.Report(name)
+		if !ok {
+			http.Error(w, fmt.Sprintf("unknown type %q", name), http.StatusNotFound)
+			return
+		}
+		reports = []layout.Report{rep}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(reports)
+}
+
+// layoutPanel summarizes each registered struct's layout.
+func layoutPanel() []dashboard.Block {
+	table := &dashboard.Table{Columns: []string{"Type", "Size", "Padding", "Fields (offset+size)", "Optimal size", "Optimal order"}}
+	for _, rep := range structLayouts.Reports() {
+		fields := make([]string, len(rep.Fields))
+		for i, f := range rep.Fields {
+			fields[i] = fmt.Sprintf("%s@%d+%d", f.Name, f.Offset, f.Size)
+		}
+		table.Rows = append(table.Rows, []any{rep.Name, rep.Size, rep.Padding,
+			strings.Join(fields, " "), rep.OptimalSize, strings.Join(rep.OptimalOrder, ", ")})
+	}
+	return []dashboard.Block{table}
+}
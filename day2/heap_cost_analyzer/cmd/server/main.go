@@ -1,15 +1,25 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
 	"fmt"
-	"log"
+	"log/slog"
+	"net"
 	"net/http"
+	"os"
+	"os/signal"
 	"runtime"
 	"strconv"
 	"sync"
+	"syscall"
 	"time"
 
 	"heap_cost_analyzer/internal/analyzer"
+	"lessonkit/logging"
+	"lessonkit/metrics"
+	"lessonkit/tracing"
 )
 
 var (
@@ -18,90 +28,226 @@ var (
 	lastHeapStats    *analyzer.RequestStats
 	lastStackStats   analyzer.RequestStats
 	metricsMu        sync.RWMutex
+
+	pooledRequestCount uint64
+	intoRequestCount   uint64
+	lastPooledStats    analyzer.RequestStats // copied out before the pooled object is released
+	lastIntoStats      analyzer.RequestStats
+)
+
+// Prometheus metrics served at /metrics; the legacy JSON lives at /api/metrics.
+var (
+	promRegistry   = metrics.NewRegistry()
+	requestMetrics = metrics.NewRequestMetrics(promRegistry)
+)
+
+func init() {
+	metrics.RegisterRuntimeMetrics(promRegistry)
+}
+
+// Server timeouts. WriteTimeout leaves room for long /profile/heap runs.
+const (
+	readHeaderTimeout = 5 * time.Second
+	readTimeout       = 10 * time.Second
+	writeTimeout      = 60 * time.Second
+	idleTimeout       = 120 * time.Second
+	shutdownTimeout   = 15 * time.Second
 )
 
+// NewMux returns the application's HTTP mux for testing and reuse.
+func NewMux() *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", dashboardHandler)
+	mux.HandleFunc("/dashboard", dashboardHandler)
+	mux.Handle(dash.StreamPath, dash.Stream())
+	mux.HandleFunc("/stats-heap", heapStatsHandler)
+	mux.HandleFunc("/stats-stack", stackStatsHandler)
+	mux.HandleFunc("/stats-pooled", pooledStatsHandler)
+	mux.HandleFunc("/stats-into", intoStatsHandler)
+	mux.HandleFunc("/batch-heap", batchHandler("pointers", "batch-heap"))
+	mux.HandleFunc("/batch-stack", batchHandler("values", "batch-stack"))
+	mux.HandleFunc("/batch-compare", batchCompareHandler)
+	mux.HandleFunc("/api/metrics", metricsJSONHandler)
+	mux.Handle("/metrics", promRegistry.Handler())
+	mux.HandleFunc("/api/history", historyJSONHandler)
+	mux.HandleFunc("/experiment", experimentHandler)
+	mux.HandleFunc("/api/layout", layoutJSONHandler)
+	mux.HandleFunc("/profile/heap", profileHeapHandler)
+	mux.HandleFunc("/profile/heap/before", profileDownloadHandler("before"))
+	mux.HandleFunc("/profile/heap/after", profileDownloadHandler("after"))
+	return mux
+}
+
+// newServer wraps handler in an http.Server with the standard timeouts.
+func newServer(addr string, handler http.Handler) *http.Server {
+	srv := &http.Server{
+		Addr:              addr,
+		Handler:           handler,
+		ReadHeaderTimeout: readHeaderTimeout,
+		ReadTimeout:       readTimeout,
+		WriteTimeout:      writeTimeout,
+		IdleTimeout:       idleTimeout,
+	}
+	// Dashboard streams never finish on their own; end them so Shutdown can drain.
+	srv.RegisterOnShutdown(dash.Close)
+	return srv
+}
+
 func main() {
-	http.HandleFunc("/", dashboardHandler)
-	http.HandleFunc("/dashboard", dashboardHandler)
-	http.HandleFunc("/stats-heap", func(w http.ResponseWriter, r *http.Request) {
-		reqIDStr := r.URL.Query().Get("id")
-		reqID, err := strconv.ParseUint(reqIDStr, 10, 64)
-		if err != nil {
-			reqID = 0
-		}
-		stats := analyzer.ProcessRequestPointer(reqID)
-		metricsMu.Lock()
-		heapRequestCount++
-		lastHeapStats = stats
-		metricsMu.Unlock()
-		fmt.Fprintf(w, "Heap Allocated Stats: %+v\n", stats)
-		log.Printf("Heap handler processed request ID %d", reqID)
-		runtime.GC()
-	})
-
-	http.HandleFunc("/stats-stack", func(w http.ResponseWriter, r *http.Request) {
-		reqIDStr := r.URL.Query().Get("id")
-		reqID, err := strconv.ParseUint(reqIDStr, 10, 64)
-		if err != nil {
-			reqID = 0
-		}
-		stats := analyzer.ProcessRequestValue(reqID)
-		metricsMu.Lock()
-		stackRequestCount++
-		lastStackStats = stats
-		metricsMu.Unlock()
-		fmt.Fprintf(w, "Stack Allocated Stats: %+v\n", stats)
-		log.Printf("Stack handler processed request ID %d", reqID)
-		runtime.GC()
-	})
-
-	http.HandleFunc("/metrics", metricsJSONHandler)
-
-	log.Printf("Server listening on :%s", "8080")
-	log.Fatal(http.ListenAndServe(":8080", nil))
+	port := os.Getenv("PORT")
+	if port == "" {
+		port = "8080"
+	}
+	addr := flag.String("addr", ":"+port, "listen address (defaults to :$PORT or :8080)")
+	flag.Parse()
+
+	logger, closeLog := logging.New(os.Stderr, logging.OptionsFromEnv())
+	slog.SetDefault(logger)
+	defer closeLog()
+	tracer, err := tracing.FromEnv("heap_cost_analyzer")
+	if err != nil {
+		logger.Error("tracing disabled", "err", err)
+		tracer = tracing.NewTracer("heap_cost_analyzer", nil)
+	}
+	defer tracer.Close()
+	fatal := func(msg string, err error) {
+		logger.Error(msg, "err", err)
+		tracer.Close()
+		closeLog()
+		os.Exit(1)
+	}
+
+	stopHistory := make(chan struct{})
+	go history.Run(stopHistory)
+	defer close(stopHistory)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	ln, err := net.Listen("tcp", *addr)
+	if err != nil {
+		fatal("listen failed", err)
+	}
+	logger.Info("server listening", "addr", ln.Addr().String())
+	if err := serve(ctx, newServer(*addr, tracing.Middleware(tracer, logging.Middleware(logger, NewMux()))), ln); err != nil {
+		fatal("server failed", err)
+	}
+	logger.Info("server stopped")
 }
 
-func dashboardHandler(w http.ResponseWriter, r *http.Request) {
-	metricsMu.RLock()
-	heapCnt := heapRequestCount
-	stackCnt := stackRequestCount
-	lastHeap := lastHeapStats
-	lastStack := lastStackStats
-	metricsMu.RUnlock()
-
-	heapID, heapTS, heapDur, heapStatus := uint64(0), int64(0), time.Duration(0), 0
-	if lastHeap != nil {
-		heapID, heapTS, heapDur, heapStatus = lastHeap.ID, lastHeap.Timestamp, lastHeap.Duration, lastHeap.Status
+// serve runs srv on ln until ctx is cancelled, then drains in-flight requests
+// for up to shutdownTimeout before returning.
+func serve(ctx context.Context, srv *http.Server, ln net.Listener) error {
+	errCh := make(chan error, 1)
+	go func() { errCh <- srv.Serve(ln) }()
+
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+	}
+
+	slog.Info("shutting down", "drain_timeout", shutdownTimeout)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		return fmt.Errorf("graceful shutdown: %w", err)
+	}
+	if err := <-errCh; err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// requestID parses the optional ?id= query parameter, defaulting to 0.
+func requestID(r *http.Request) uint64 {
+	reqID, err := strconv.ParseUint(r.URL.Query().Get("id"), 10, 64)
+	if err != nil {
+		return 0
 	}
-	stackID, stackTS, stackDur, stackStatus := lastStack.ID, lastStack.Timestamp, lastStack.Duration, lastStack.Status
-
-	w.Header().Set("Content-Type", "text/html; charset=utf-8")
-	html := fmt.Sprintf(`<!DOCTYPE html>
-<html>
-<head><title>Heap Cost Analyzer Dashboard</title>
-<meta http-equiv="refresh" content="2">
-<style>
-body{font-family:sans-serif;margin:20px;background:#1a1a2e;color:#eee;}
-h1{color:#0f4;}
-.metric{background:#16213e;padding:12px;margin:8px 0;border-radius:8px;}
-.metric strong{color:#0f4;}
-.nav a{color:#0f4;margin-right:12px;}
-</style>
-</head>
-<body>
-<h1>Heap Cost Analyzer Dashboard</h1>
-<p class="nav"><a href="/">Dashboard</a> | <a href="/stats-heap?id=1">Demo Heap</a> | <a href="/stats-stack?id=2">Demo Stack</a> | <a href="/metrics">JSON Metrics</a></p>
-<div class="metric"><strong>Heap requests:</strong> %d</div>
-<div class="metric"><strong>Stack requests:</strong> %d</div>
-<div class="metric"><strong>Last heap stats:</strong> ID=%d Timestamp=%d Duration=%s Status=%d</div>
-<div class="metric"><strong>Last stack stats:</strong> ID=%d Timestamp=%d Duration=%s Status=%d</div>
-<p><em>Auto-refresh 2s. Hit Demo links to update metrics.</em></p>
-</body>
-</html>`,
-		heapCnt, stackCnt,
-		heapID, heapTS, heapDur, heapStatus,
-		stackID, stackTS, stackDur, stackStatus)
-	fmt.Fprint(w, html)
+	return reqID
+}
+
+// observeRequest records handler latency for endpoint. Handlers call it before
+// their forced GC so the series reflects request handling only.
+func observeRequest(endpoint string, start time.Time) {
+	elapsed := time.Since(start)
+	history.Observe(endpoint, elapsed)
+	requestMetrics.Observe(endpoint, elapsed)
+}
+
+func heapStatsHandler(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	reqID := requestID(r)
+	_, span := tracing.Start(r.Context(), "process")
+	stats := analyzer.ProcessRequestPointer(reqID)
+	span.End()
+	metricsMu.Lock()
+	heapRequestCount++
+	lastHeapStats = stats
+	metricsMu.Unlock()
+	fmt.Fprintf(w, "Heap Allocated Stats: %+v\n", stats)
+	observeRequest("heap", start)
+	slog.DebugContext(r.Context(), "processed request", "handler", "heap", "id", reqID)
+	_, span = tracing.Start(r.Context(), "gc")
+	runtime.GC()
+	span.End()
+}
+
+func stackStatsHandler(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	reqID := requestID(r)
+	_, span := tracing.Start(r.Context(), "process")
+	stats := analyzer.ProcessRequestValue(reqID)
+	span.End()
+	metricsMu.Lock()
+	stackRequestCount++
+	lastStackStats = stats
+	metricsMu.Unlock()
+	fmt.Fprintf(w, "Stack Allocated Stats: %+v\n", stats)
+	observeRequest("stack", start)
+	slog.DebugContext(r.Context(), "processed request", "handler", "stack", "id", reqID)
+	_, span = tracing.Start(r.Context(), "gc")
+	runtime.GC()
+	span.End()
+}
+
+func pooledStatsHandler(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	reqID := requestID(r)
+	_, span := tracing.Start(r.Context(), "process")
+	stats := analyzer.ProcessRequestPooled(reqID)
+	span.End()
+	metricsMu.Lock()
+	pooledRequestCount++
+	lastPooledStats = *stats
+	metricsMu.Unlock()
+	fmt.Fprintf(w, "Pooled Stats: %+v\n", stats)
+	stats.Release()
+	observeRequest("pooled", start)
+	slog.DebugContext(r.Context(), "processed request", "handler", "pooled", "id", reqID)
+	_, span = tracing.Start(r.Context(), "gc")
+	runtime.GC()
+	span.End()
+}
+
+func intoStatsHandler(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	reqID := requestID(r)
+	_, span := tracing.Start(r.Context(), "process")
+	var stats analyzer.RequestStats
+	analyzer.ProcessRequestInto(reqID, &stats)
+	span.End()
+	metricsMu.Lock()
+	intoRequestCount++
+	lastIntoStats = stats
+	metricsMu.Unlock()
+	fmt.Fprintf(w, "Caller-Provided Stats: %+v\n", stats)
+	observeRequest("into", start)
+	slog.DebugContext(r.Context(), "processed request", "handler", "into", "id", reqID)
+	_, span = tracing.Start(r.Context(), "gc")
+	runtime.GC()
+	span.End()
 }
 
 func metricsJSONHandler(w http.ResponseWriter, r *http.Request) {
@@ -109,10 +255,10 @@ func metricsJSONHandler(w http.ResponseWriter, r *http.Request) {
 	defer metricsMu.RUnlock()
 	w.Header().Set("Content-Type", "application/json")
 	if lastHeapStats != nil {
-		fmt.Fprintf(w, `{"heap_requests":%d,"stack_requests":%d,"last_heap":%+v,"last_stack":%+v}`,
-			heapRequestCount, stackRequestCount, lastHeapStats, lastStackStats)
+		fmt.Fprintf(w, `{"heap_requests":%d,"stack_requests":%d,"pooled_requests":%d,"into_requests":%d,"last_heap":%+v,"last_stack":%+v}`,
+			heapRequestCount, stackRequestCount, pooledRequestCount, intoRequestCount, lastHeapStats, lastStackStats)
 	} else {
-		fmt.Fprintf(w, `{"heap_requests":%d,"stack_requests":%d,"last_stack":%+v}`,
-			heapRequestCount, stackRequestCount, lastStackStats)
+		fmt.Fprintf(w, `{"heap_requests":%d,"stack_requests":%d,"pooled_requests":%d,"into_requests":%d,"last_stack":%+v}`,
+			heapRequestCount, stackRequestCount, pooledRequestCount, intoRequestCount, lastStackStats)
 	}
 }
@@ -1,10 +1,17 @@
 package main
 
 import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"runtime"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestDashboardHandler(t *testing.T) {
@@ -34,3 +41,260 @@ func TestMetricsJSONHandler(t *testing.T) {
 	}
 }
 
+func TestProfileHeapHandler(t *testing.T) {
+	if runtime.MemProfileRate != memProfileRate {
+		t.Fatalf("MemProfileRate = %d, want %d", runtime.MemProfileRate, memProfileRate)
+	}
+	req := httptest.NewRequest("GET", "/profile/heap?scenario=pointer&iterations=1000&top=5", nil)
+	w := httptest.NewRecorder()
+	profileHeapHandler(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("profile status = %d, want 200: %s", w.Code, w.Body.String())
+	}
+	if !strings.Contains(w.Body.String(), `"scenario":"pointer"`) {
+		t.Errorf("profile body = %s", w.Body.String())
+	}
+
+	for _, which := range []string{"before", "after"} {
+		w = httptest.NewRecorder()
+		profileDownloadHandler(which)(w, httptest.NewRequest("GET", "/profile/heap/"+which, nil))
+		if w.Code != http.StatusOK || w.Body.Len() == 0 {
+			t.Errorf("%s download status = %d, len = %d", which, w.Code, w.Body.Len())
+		}
+	}
+
+	w = httptest.NewRecorder()
+	dashboardHandler(w, httptest.NewRequest("GET", "/", nil))
+	if !strings.Contains(w.Body.String(), "Heap profile diff") {
+		t.Error("dashboard body missing heap profile section")
+	}
+}
+
+func TestProfileHeapHandlerUnknownScenario(t *testing.T) {
+	req := httptest.NewRequest("GET", "/profile/heap?scenario=bogus", nil)
+	w := httptest.NewRecorder()
+	profileHeapHandler(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("status = %d, want 400", w.Code)
+	}
+}
+
+func TestHistoryJSONHandler(t *testing.T) {
+	history.Observe("heap", time.Millisecond)
+	history.Tick(time.Now())
+
+	req := httptest.NewRequest("GET", "/api/history?endpoint=heap&last=1m", nil)
+	w := httptest.NewRecorder()
+	historyJSONHandler(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("history status = %d, want 200: %s", w.Code, w.Body.String())
+	}
+	if !strings.Contains(w.Body.String(), `"endpoint":"heap"`) || !strings.Contains(w.Body.String(), `"p99_ns"`) {
+		t.Errorf("history body = %s", w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	historyJSONHandler(w, httptest.NewRequest("GET", "/api/history?endpoint=nope", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("unknown endpoint status = %d, want 404", w.Code)
+	}
+}
+
+func TestPrometheusMetrics(t *testing.T) {
+	requestMetrics.Observe("heap", time.Millisecond)
+	req := httptest.NewRequest("GET", "/metrics", nil)
+	w := httptest.NewRecorder()
+	promRegistry.Handler().ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Errorf("metrics status = %d, want 200", w.Code)
+	}
+	for _, want := range []string{`http_requests_total{handler="heap"}`, "go_gc_heap_allocs_bytes_total"} {
+		if !strings.Contains(w.Body.String(), want) {
+			t.Errorf("metrics body missing %q", want)
+		}
+	}
+}
+
+func TestNewMuxEndToEnd(t *testing.T) {
+	srv := httptest.NewServer(NewMux())
+	defer srv.Close()
+	for path, want := range map[string]string{
+		"/stats-heap?id=7":   "Heap Allocated Stats",
+		"/stats-stack?id=8":  "Stack Allocated Stats",
+		"/stats-pooled?id=9": "Pooled Stats",
+		"/stats-into?id=10":  "Caller-Provided Stats",
+		"/dashboard":         "Heap Cost Analyzer Dashboard",
+	} {
+		resp, err := http.Get(srv.URL + path)
+		if err != nil {
+			t.Fatalf("GET %s: %v", path, err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK || !strings.Contains(string(body), want) {
+			t.Errorf("GET %s = %d %q, want 200 containing %q", path, resp.StatusCode, body, want)
+		}
+	}
+}
+
+func TestDashboardStream(t *testing.T) {
+	srv := httptest.NewServer(NewMux())
+	defer srv.Close()
+	resp, err := http.Get(srv.URL + "/dashboard/stream")
+	if err != nil {
+		t.Fatalf("GET stream: %v", err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("Content-Type = %q, want text/event-stream", ct)
+	}
+	sc := bufio.NewScanner(resp.Body)
+	sc.Buffer(nil, 1<<20)
+	for sc.Scan() {
+		if line := sc.Text(); strings.HasPrefix(line, "data: ") && strings.Contains(line, `"id":"requests"`) {
+			if !strings.Contains(line, "Heap requests") {
+				t.Errorf("requests panel event = %s", line)
+			}
+			return
+		}
+	}
+	t.Fatal("stream ended without a requests panel event")
+}
+
+func TestServeGracefulShutdown(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	release := make(chan struct{})
+	mux := http.NewServeMux()
+	mux.HandleFunc("/slow", func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		w.Write([]byte("drained"))
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- serve(ctx, newServer(ln.Addr().String(), mux), ln) }()
+
+	respCh := make(chan string, 1)
+	go func() {
+		resp, err := http.Get("http://" + ln.Addr().String() + "/slow")
+		if err != nil {
+			respCh <- err.Error()
+			return
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		respCh <- string(body)
+	}()
+
+	// Wait for the request to be in flight before signalling shutdown.
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+
+	if got := <-respCh; got != "drained" {
+		t.Errorf("in-flight response = %q, want drained", got)
+	}
+	if err := <-done; err != nil {
+		t.Errorf("serve returned %v", err)
+	}
+}
+
+func TestExperimentHandler(t *testing.T) {
+	req := httptest.NewRequest("GET", "/experiment?concurrency=2&duration=20ms&format=json", nil)
+	w := httptest.NewRecorder()
+	experimentHandler(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("experiment status = %d, want 200: %s", w.Code, w.Body.String())
+	}
+	for _, want := range []string{`"name":"pointer"`, `"name":"value"`, `"name":"pooled"`, `"name":"into"`, `"ops_per_sec"`} {
+		if !strings.Contains(w.Body.String(), want) {
+			t.Errorf("experiment body missing %q", want)
+		}
+	}
+
+	w = httptest.NewRecorder()
+	experimentHandler(w, httptest.NewRequest("GET", "/experiment?concurrency=1&duration=10ms", nil))
+	if !strings.Contains(w.Body.String(), "Request Stats Strategy Experiment") {
+		t.Error("experiment page missing title")
+	}
+
+	w = httptest.NewRecorder()
+	experimentHandler(w, httptest.NewRequest("GET", "/experiment?duration=1h", nil))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("oversized duration status = %d, want 400", w.Code)
+	}
+}
+
+func TestLayoutJSONHandler(t *testing.T) {
+	req := httptest.NewRequest("GET", "/api/layout?type=analyzer.RequestStats", nil)
+	w := httptest.NewRecorder()
+	layoutJSONHandler(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("layout status = %d, want 200", w.Code)
+	}
+	if !strings.Contains(w.Body.String(), `"size":32`) || !strings.Contains(w.Body.String(), `"name":"Timestamp"`) {
+		t.Errorf("layout body = %s", w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	layoutJSONHandler(w, httptest.NewRequest("GET", "/api/layout", nil))
+	if !strings.Contains(w.Body.String(), "arena.MyData") {
+		t.Error("layout body missing arena.MyData")
+	}
+}
+
+func TestBatchHandlers(t *testing.T) {
+	for _, tc := range []struct {
+		name, contentType, body string
+	}{
+		{"json", "application/json", "[1, 2, 3]"},
+		{"ndjson", "text/plain", "1\n2\n\n3\n"},
+	} {
+		req := httptest.NewRequest("POST", "/batch-heap", strings.NewReader(tc.body))
+		req.Header.Set("Content-Type", tc.contentType)
+		w := httptest.NewRecorder()
+		batchHandler("pointers", "batch-heap")(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s: status = %d: %s", tc.name, w.Code, w.Body.String())
+		}
+		if !strings.Contains(w.Body.String(), `"mode":"pointers","count":3`) {
+			t.Errorf("%s: body = %s", tc.name, w.Body.String())
+		}
+	}
+
+	w := httptest.NewRecorder()
+	batchHandler("values", "batch-stack")(w, httptest.NewRequest("POST", "/batch-stack", strings.NewReader("1\nnope\n")))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("invalid ID status = %d, want 400", w.Code)
+	}
+}
+
+func TestBatchCompareHandler(t *testing.T) {
+	req := httptest.NewRequest("GET", "/batch-compare?n=20000", nil)
+	w := httptest.NewRecorder()
+	batchCompareHandler(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("compare status = %d: %s", w.Code, w.Body.String())
+	}
+	var out batchCompareJSON
+	if err := json.Unmarshal(w.Body.Bytes(), &out); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	// One backing array for values vs one object per element plus the array for pointers.
+	if out.Pointers.AllocObjects < 10000 || out.Values.AllocObjects > 10 {
+		t.Errorf("alloc objects values/pointers = %d/%d", out.Values.AllocObjects, out.Pointers.AllocObjects)
+	}
+	if out.ScanHeapDelta <= 0 {
+		t.Errorf("scan heap delta = %d, want > 0", out.ScanHeapDelta)
+	}
+
+	w = httptest.NewRecorder()
+	promRegistry.Handler().ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	if want := `http_requests_total{handler="batch-compare"}`; !strings.Contains(w.Body.String(), want) {
+		t.Errorf("metrics body missing %q", want)
+	}
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"heap_cost_analyzer/internal/analyzer"
+	"heap_cost_analyzer/internal/heapprof"
+	"lessonkit/dashboard"
+)
+
+const (
+	// memProfileRate samples roughly one allocation per 4KB instead of the
+	// default 512KB so that small structs like RequestStats show up in diffs.
+	memProfileRate = 4096
+
+	defaultProfileIterations = 10000
+	maxProfileIterations     = 1000000
+	defaultProfileTop        = 10
+)
+
+// Set the sampling rate before main runs, so the server and the tests take
+// every profile at memProfileRate.
+func init() {
+	runtime.MemProfileRate = memProfileRate
+}
+
+// Sinks keep scenario results reachable so the compiler cannot elide the work.
+var (
+	pointerSink *analyzer.RequestStats
+	valueSink   analyzer.RequestStats
+)
+
+// profileScenarios are the workloads that can be run between two heap snapshots.
+var profileScenarios = map[string]func(i int){
+	"pointer": func(i int) { pointerSink = analyzer.ProcessRequestPointer(uint64(i)) },
+	"value":   func(i int) { valueSink = analyzer.ProcessRequestValue(uint64(i)) },
+	"pooled": func(i int) {
+		stats := analyzer.ProcessRequestPooled(uint64(i))
+		valueSink = *stats
+		stats.Release()
+	},
+	"into": func(i int) { analyzer.ProcessRequestInto(uint64(i), &valueSink) },
+}
+
+var (
+	profileMu   sync.Mutex // serializes profile runs; heap snapshots are process-wide
+	lastProfile *heapprof.Result
+)
+
+type profileDeltaJSON struct {
+	Func         string `json:"func"`
+	InUseBytes   int64  `json:"inuse_bytes"`
+	InUseObjects int64  `json:"inuse_objects"`
+	AllocBytes   int64  `json:"alloc_bytes"`
+	AllocObjects int64  `json:"alloc_objects"`
+}
+
+type profileResultJSON struct {
+	Scenario   string             `json:"scenario"`
+	Iterations int                `json:"iterations"`
+	ElapsedNs  int64              `json:"elapsed_ns"`
+	Top        []profileDeltaJSON `json:"top"`
+	Before     string             `json:"before_pprof"`
+	After      string             `json:"after_pprof"`
+}
+
+// profileHeapHandler runs a scenario between two heap snapshots and returns the
+// top-N per-function diff as JSON. The raw profiles stay downloadable until the next run.
+func profileHeapHandler(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	scenario := q.Get("scenario")
+	fn, ok := profileScenarios[scenario]
+	if !ok {
+		http.Error(w, fmt.Sprintf("unknown 'scenario' parameter; want one of %s", scenarioNames()), http.StatusBadRequest)
+		return
+	}
+	iterations, err := intParam(q.Get("iterations"), defaultProfileIterations)
+	if err != nil || iterations <= 0 || iterations > maxProfileIterations {
+		http.Error(w, fmt.Sprintf("invalid 'iterations' parameter (1-%d)", maxProfileIterations), http.StatusBadRequest)
+		return
+	}
+	top, err := intParam(q.Get("top"), defaultProfileTop)
+	if err != nil || top <= 0 {
+		http.Error(w, "invalid 'top' parameter", http.StatusBadRequest)
+		return
+	}
+
+	profileMu.Lock()
+	res, err := heapprof.Run(scenario, iterations, fn)
+	if err == nil {
+		lastProfile = res
+	}
+	profileMu.Unlock()
+	if err != nil {
+		http.Error(w, fmt.Sprintf("profile error: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	out := profileResultJSON{
+		Scenario:   res.Scenario,
+		Iterations: res.Iterations,
+		ElapsedNs:  res.Elapsed.Nanoseconds(),
+		Top:        []profileDeltaJSON{},
+		Before:     "/profile/heap/before",
+		After:      "/profile/heap/after",
+	}
+	for _, d := range res.Top(top) {
+		out.Top = append(out.Top, profileDeltaJSON(d))
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(out)
+}
+
+// profileDownloadHandler serves the before or after pprof file of the last run.
+func profileDownloadHandler(which string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		profileMu.Lock()
+		res := lastProfile
+		profileMu.Unlock()
+		if res == nil {
+			http.Error(w, "no heap profile captured yet", http.StatusNotFound)
+			return
+		}
+		snap := res.Before
+		if which == "after" {
+			snap = res.After
+		}
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Header().Set("Content-Disposition",
+			fmt.Sprintf(`attachment; filename="heap-%s-%s.pb.gz"`, res.Scenario, which))
+		w.Write(snap.Profile)
+	}
+}
+
+// profilePanel renders the last profile diff as a table.
+func profilePanel() []dashboard.Block {
+	profileMu.Lock()
+	res := lastProfile
+	profileMu.Unlock()
+
+	if res == nil {
+		return []dashboard.Block{dashboard.Text("No profile captured yet.")}
+	}
+	table := &dashboard.Table{Columns: []string{"Function", "Alloc bytes", "Alloc objects", "In-use bytes", "In-use objects"}}
+	for _, d := range res.Top(defaultProfileTop) {
+		table.Rows = append(table.Rows, []any{d.Func, d.AllocBytes, d.AllocObjects, d.InUseBytes, d.InUseObjects})
+	}
+	return []dashboard.Block{
+		dashboard.Cards{
+			{Label: "Scenario", Value: res.Scenario},
+			{Label: "Iterations", Value: res.Iterations},
+			{Label: "Elapsed", Value: res.Elapsed},
+		},
+		dashboard.Links{
+			{Label: "before.pb.gz", URL: "/profile/heap/before"},
+			{Label: "after.pb.gz", URL: "/profile/heap/after"},
+		},
+		table,
+	}
+}
+
+// profileActions links each scenario to a 10k-iteration profile run.
+func profileActions() []dashboard.Block {
+	return []dashboard.Block{dashboard.Links{
+		{Label: "Profile Pointer (10k)", URL: "/profile/heap?scenario=pointer&iterations=10000"},
+		{Label: "Profile Value (10k)", URL: "/profile/heap?scenario=value&iterations=10000"},
+		{Label: "Profile Pooled (10k)", URL: "/profile/heap?scenario=pooled&iterations=10000"},
+		{Label: "Profile Into (10k)", URL: "/profile/heap?scenario=into&iterations=10000"},
+	}}
+}
+
+func scenarioNames() string {
+	names := make([]string, 0, len(profileScenarios))
+	for name := range profileScenarios {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+func intParam(s string, def int) (int, error) {
+	if s == "" {
+		return def, nil
+	}
+	return strconv.Atoi(s)
+}
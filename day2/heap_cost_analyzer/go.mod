@@ -1,3 +1,15 @@
 module heap_cost_analyzer
 
-go 1.22
+// 1.22.0 rather than 1.22: the go command requires at least the go version
+// of every dependency, and memory_arena_lesson declares go 1.22.0.
+go 1.22.0
+
+require (
+	lessonkit v0.0.0
+	memory_arena_lesson v0.0.0
+)
+
+replace (
+	lessonkit => ../../lessonkit
+	memory_arena_lesson => ../../day5/memory_arena_lesson
+)
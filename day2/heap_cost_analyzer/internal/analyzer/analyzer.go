@@ -2,6 +2,7 @@ package analyzer
 
 import (
 	"net/http"
+	"sync"
 	"time"
 )
 
@@ -36,3 +37,61 @@ func ProcessRequestValue(requestID uint64) RequestStats {
 	}
 	return stats
 }
+
+// statsPool recycles RequestStats handed out by ProcessRequestPooled.
+var statsPool = sync.Pool{
+	New: func() interface{} {
+		return new(RequestStats)
+	},
+}
+
+// ProcessRequestPooled simulates processing a request and generating stats.
+// It returns a *pointer* taken from a sync.Pool, so steady-state calls do not
+// allocate. The caller must call Release once it no longer needs the stats.
+func ProcessRequestPooled(requestID uint64) *RequestStats {
+	stats := statsPool.Get().(*RequestStats)
+	stats.ID = requestID
+	stats.Timestamp = time.Now().UnixNano()
+	stats.Duration = time.Millisecond * 10 // Simulate work
+	stats.Status = http.StatusOK
+	return stats
+}
+
+// Release zeroes stats and returns it to the pool used by ProcessRequestPooled.
+// stats must not be used after Release.
+func (s *RequestStats) Release() {
+	*s = RequestStats{}
+	statsPool.Put(s)
+}
+
+// ProcessRequestInto simulates processing a request and writes the stats into
+// a caller-provided struct. Where the stats live is the caller's decision;
+// this function never allocates.
+func ProcessRequestInto(requestID uint64, stats *RequestStats) {
+	stats.ID = requestID
+	stats.Timestamp = time.Now().UnixNano()
+	stats.Duration = time.Millisecond * 10 // Simulate work
+	stats.Status = http.StatusOK
+}
+
+// ProcessBatchValues processes every ID and returns the stats by value in one
+// contiguous slice. RequestStats holds no pointers, so the backing array is
+// a single object the GC never needs to scan.
+func ProcessBatchValues(ids []uint64) []RequestStats {
+	batch := make([]RequestStats, len(ids))
+	for i, id := range ids {
+		ProcessRequestInto(id, &batch[i])
+	}
+	return batch
+}
+
+// ProcessBatchPointers processes every ID and returns a slice of pointers.
+// Each element is a separate heap object, and the slice itself must be scanned
+// by the GC to find them.
+func ProcessBatchPointers(ids []uint64) []*RequestStats {
+	batch := make([]*RequestStats, len(ids))
+	for i, id := range ids {
+		batch[i] = ProcessRequestPointer(id)
+	}
+	return batch
+}
@@ -24,3 +24,81 @@ func TestProcessRequestValue(t *testing.T) {
 		t.Errorf("Status = %d, want 200", stats.Status)
 	}
 }
+
+func TestProcessRequestPooled(t *testing.T) {
+	stats := ProcessRequestPooled(7)
+	got := *stats // stats must not be read after Release
+	stats.Release()
+	if got.ID != 7 || got.Status != 200 {
+		t.Errorf("stats = %+v, want ID 7 status 200", got)
+	}
+	// Whatever the pool hands out next, released or new, starts zeroed.
+	next := statsPool.Get().(*RequestStats)
+	defer statsPool.Put(next)
+	if *next != (RequestStats{}) {
+		t.Errorf("pooled stats not zeroed: %+v", *next)
+	}
+}
+
+func TestProcessRequestInto(t *testing.T) {
+	var stats RequestStats
+	ProcessRequestInto(11, &stats)
+	if stats.ID != 11 || stats.Status != 200 {
+		t.Errorf("stats = %+v, want ID 11 status 200", stats)
+	}
+	allocs := testing.AllocsPerRun(100, func() { ProcessRequestInto(1, &stats) })
+	if allocs != 0 {
+		t.Errorf("ProcessRequestInto allocs = %.1f, want 0", allocs)
+	}
+}
+
+var (
+	benchPointer *RequestStats
+	benchValue   RequestStats
+)
+
+func BenchmarkProcessRequestPointer(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		benchPointer = ProcessRequestPointer(uint64(i))
+	}
+}
+
+func BenchmarkProcessRequestValue(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		benchValue = ProcessRequestValue(uint64(i))
+	}
+}
+
+func BenchmarkProcessRequestPooled(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		stats := ProcessRequestPooled(uint64(i))
+		benchValue = *stats
+		stats.Release()
+	}
+}
+
+func BenchmarkProcessRequestInto(b *testing.B) {
+	b.ReportAllocs()
+	var stats RequestStats
+	for i := 0; i < b.N; i++ {
+		ProcessRequestInto(uint64(i), &stats)
+	}
+	benchValue = stats
+}
+
+func TestProcessBatch(t *testing.T) {
+	ids := []uint64{3, 1, 4}
+	values := ProcessBatchValues(ids)
+	pointers := ProcessBatchPointers(ids)
+	if len(values) != len(ids) || len(pointers) != len(ids) {
+		t.Fatalf("batch lengths = %d/%d, want %d", len(values), len(pointers), len(ids))
+	}
+	for i, id := range ids {
+		if values[i].ID != id || pointers[i].ID != id {
+			t.Errorf("batch[%d] IDs = %d/%d, want %d", i, values[i].ID, pointers[i].ID, id)
+		}
+	}
+}
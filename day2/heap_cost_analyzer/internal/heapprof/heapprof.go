@@ -0,0 +1,196 @@
+package heapprof
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"runtime"
+	"runtime/pprof"
+	"sort"
+	"strings"
+	"time"
+)
+
+// FuncStats aggregates heap profile samples attributed to a single function.
+// Values are scaled by the memory profiling rate, the same way go tool pprof does.
+type FuncStats struct {
+	Func         string
+	InUseBytes   int64
+	InUseObjects int64
+	AllocBytes   int64
+	AllocObjects int64
+}
+
+// Snapshot is a heap profile taken at a point in time.
+// Profile holds the raw pprof encoding so it can be downloaded as-is.
+type Snapshot struct {
+	Taken   time.Time
+	Funcs   map[string]FuncStats
+	Profile []byte
+}
+
+// Delta is the change in heap usage for one function between two snapshots.
+type Delta struct {
+	Func         string
+	InUseBytes   int64
+	InUseObjects int64
+	AllocBytes   int64
+	AllocObjects int64
+}
+
+// Result is the outcome of running a workload between two heap snapshots.
+type Result struct {
+	Scenario   string
+	Iterations int
+	Elapsed    time.Duration
+	Before     *Snapshot
+	After      *Snapshot
+	Deltas     []Delta
+}
+
+// Capture forces a GC so the profile reflects the current heap, then records
+// both the raw pprof heap profile and a per-function summary of it.
+func Capture() (*Snapshot, error) {
+	runtime.GC()
+
+	var buf bytes.Buffer
+	if err := pprof.Lookup("heap").WriteTo(&buf, 0); err != nil {
+		return nil, fmt.Errorf("write heap profile: %w", err)
+	}
+
+	// MemProfile may grow between the sizing call and the fill call, so retry
+	// with some headroom until the records fit.
+	var records []runtime.MemProfileRecord
+	n, _ := runtime.MemProfile(nil, true)
+	for {
+		records = make([]runtime.MemProfileRecord, n+50)
+		var ok bool
+		n, ok = runtime.MemProfile(records, true)
+		if ok {
+			records = records[:n]
+			break
+		}
+	}
+
+	rate := int64(runtime.MemProfileRate)
+	funcs := make(map[string]FuncStats)
+	for i := range records {
+		r := &records[i]
+		name := allocSite(r.Stack())
+		allocObjs, allocBytes := scaleHeapSample(r.AllocObjects, r.AllocBytes, rate)
+		inUseObjs, inUseBytes := scaleHeapSample(r.InUseObjects(), r.InUseBytes(), rate)
+		fs := funcs[name]
+		fs.Func = name
+		fs.AllocObjects += allocObjs
+		fs.AllocBytes += allocBytes
+		fs.InUseObjects += inUseObjs
+		fs.InUseBytes += inUseBytes
+		funcs[name] = fs
+	}
+
+	return &Snapshot{
+		Taken:   time.Now(),
+		Funcs:   funcs,
+		Profile: buf.Bytes(),
+	}, nil
+}
+
+// Run captures a snapshot, calls fn iterations times, captures a second snapshot
+// and returns the per-function difference between them.
+func Run(scenario string, iterations int, fn func(i int)) (*Result, error) {
+	if iterations <= 0 {
+		return nil, fmt.Errorf("iterations must be positive")
+	}
+	before, err := Capture()
+	if err != nil {
+		return nil, err
+	}
+	start := time.Now()
+	for i := 0; i < iterations; i++ {
+		fn(i)
+	}
+	elapsed := time.Since(start)
+	after, err := Capture()
+	if err != nil {
+		return nil, err
+	}
+	return &Result{
+		Scenario:   scenario,
+		Iterations: iterations,
+		Elapsed:    elapsed,
+		Before:     before,
+		After:      after,
+		Deltas:     Diff(before, after),
+	}, nil
+}
+
+// Diff returns the per-function change between two snapshots, ordered by
+// allocated bytes (largest first). Functions with no change are omitted.
+func Diff(before, after *Snapshot) []Delta {
+	var deltas []Delta
+	for name, a := range after.Funcs {
+		b := before.Funcs[name]
+		d := Delta{
+			Func:         name,
+			InUseBytes:   a.InUseBytes - b.InUseBytes,
+			InUseObjects: a.InUseObjects - b.InUseObjects,
+			AllocBytes:   a.AllocBytes - b.AllocBytes,
+			AllocObjects: a.AllocObjects - b.AllocObjects,
+		}
+		if d.AllocBytes == 0 && d.InUseBytes == 0 {
+			continue
+		}
+		deltas = append(deltas, d)
+	}
+	sort.Slice(deltas, func(i, j int) bool {
+		if deltas[i].AllocBytes != deltas[j].AllocBytes {
+			return deltas[i].AllocBytes > deltas[j].AllocBytes
+		}
+		return deltas[i].Func < deltas[j].Func
+	})
+	return deltas
+}
+
+// Top returns at most n deltas from the front of the result.
+func (r *Result) Top(n int) []Delta {
+	if n <= 0 || n >= len(r.Deltas) {
+		return r.Deltas
+	}
+	return r.Deltas[:n]
+}
+
+// allocSite returns the first non-runtime function in an allocation stack.
+func allocSite(stack []uintptr) string {
+	frames := runtime.CallersFrames(stack)
+	first := ""
+	for {
+		frame, more := frames.Next()
+		if first == "" {
+			first = frame.Function
+		}
+		if frame.Function != "" && !strings.HasPrefix(frame.Function, "runtime.") {
+			return frame.Function
+		}
+		if !more {
+			break
+		}
+	}
+	if first == "" {
+		return "<unknown>"
+	}
+	return first
+}
+
+// scaleHeapSample undoes the sampling bias of the memory profiler.
+// It mirrors the scaling applied by runtime/pprof when writing heap profiles.
+func scaleHeapSample(count, size, rate int64) (int64, int64) {
+	if count == 0 || size == 0 {
+		return 0, 0
+	}
+	if rate <= 1 {
+		return count, size
+	}
+	avgSize := float64(size) / float64(count)
+	scale := 1 / (1 - math.Exp(-avgSize/float64(rate)))
+	return int64(float64(count) * scale), int64(float64(size) * scale)
+}
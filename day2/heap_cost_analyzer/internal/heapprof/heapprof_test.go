@@ -0,0 +1,69 @@
+package heapprof
+
+import (
+	"os"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestMain(m *testing.M) {
+	// Record every allocation so small workloads show up deterministically.
+	runtime.MemProfileRate = 1
+	os.Exit(m.Run())
+}
+
+type payload struct {
+	a, b, c, d uint64
+}
+
+var sink *payload
+
+//go:noinline
+func allocPayload(i int) *payload {
+	return &payload{a: uint64(i)}
+}
+
+func TestRunAttributesAllocations(t *testing.T) {
+	const iterations = 1000
+	res, err := Run("test", iterations, func(i int) {
+		sink = allocPayload(i)
+	})
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if len(res.Before.Profile) == 0 || len(res.After.Profile) == 0 {
+		t.Fatal("snapshot missing raw pprof data")
+	}
+	var found *Delta
+	for i := range res.Deltas {
+		if strings.HasSuffix(res.Deltas[i].Func, ".allocPayload") {
+			found = &res.Deltas[i]
+			break
+		}
+	}
+	if found == nil {
+		t.Fatalf("allocPayload not in deltas: %+v", res.Deltas)
+	}
+	if found.AllocObjects < iterations {
+		t.Errorf("AllocObjects = %d, want >= %d", found.AllocObjects, iterations)
+	}
+}
+
+func TestRunRejectsNonPositiveIterations(t *testing.T) {
+	if _, err := Run("test", 0, func(int) {}); err == nil {
+		t.Error("Run with 0 iterations should fail")
+	}
+}
+
+func TestScaleHeapSample(t *testing.T) {
+	if c, s := scaleHeapSample(0, 0, 512*1024); c != 0 || s != 0 {
+		t.Errorf("zero sample scaled to %d/%d", c, s)
+	}
+	if c, s := scaleHeapSample(3, 96, 1); c != 3 || s != 96 {
+		t.Errorf("rate 1 scaled to %d/%d, want 3/96", c, s)
+	}
+	if c, _ := scaleHeapSample(1, 32, 512*1024); c <= 1 {
+		t.Errorf("sampled count not scaled up: %d", c)
+	}
+}
@@ -0,0 +1,137 @@
+package loadtest
+
+import (
+	"fmt"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Config controls how hard and how long a scenario is driven.
+type Config struct {
+	Concurrency int
+	Duration    time.Duration
+}
+
+// Validate reports whether the config can be run.
+func (c Config) Validate() error {
+	if c.Concurrency <= 0 {
+		return fmt.Errorf("concurrency must be positive")
+	}
+	if c.Duration <= 0 {
+		return fmt.Errorf("duration must be positive")
+	}
+	return nil
+}
+
+// Scenario is a named operation driven by the runner. NewOp is called once per
+// run with the worker count so scenarios can set up per-worker state.
+type Scenario struct {
+	Name  string
+	NewOp func(workers int) func(worker int, i uint64)
+}
+
+// Result captures throughput and GC behaviour for one scenario run.
+type Result struct {
+	Name             string        `json:"name"`
+	Concurrency      int           `json:"concurrency"`
+	Ops              uint64        `json:"ops"`
+	Elapsed          time.Duration `json:"elapsed_ns"`
+	OpsPerSec        float64       `json:"ops_per_sec"`
+	AllocBytes       uint64        `json:"alloc_bytes"`
+	AllocObjects     uint64        `json:"alloc_objects"`
+	AllocBytesPerSec float64       `json:"alloc_bytes_per_sec"`
+	BytesPerOp       float64       `json:"bytes_per_op"`
+	AllocsPerOp      float64       `json:"allocs_per_op"`
+	NumGC            uint32        `json:"num_gc"`
+	PauseTotal       time.Duration `json:"pause_total_ns"`
+	MaxPause         time.Duration `json:"max_pause_ns"`
+}
+
+// Run drives op from cfg.Concurrency goroutines for cfg.Duration and reports
+// throughput plus the allocation and GC activity observed during the run.
+func Run(s Scenario, cfg Config) (Result, error) {
+	if err := cfg.Validate(); err != nil {
+		return Result{}, err
+	}
+	op := s.NewOp(cfg.Concurrency)
+
+	// Start from a clean heap so earlier garbage is not billed to this run.
+	runtime.GC()
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+
+	var (
+		ops  atomic.Uint64
+		stop atomic.Bool
+		wg   sync.WaitGroup
+	)
+	start := time.Now()
+	for w := 0; w < cfg.Concurrency; w++ {
+		wg.Add(1)
+		go func(worker int) {
+			defer wg.Done()
+			var n uint64
+			for !stop.Load() {
+				op(worker, n)
+				n++
+			}
+			ops.Add(n)
+		}(w)
+	}
+	time.Sleep(cfg.Duration)
+	stop.Store(true)
+	wg.Wait()
+	elapsed := time.Since(start)
+	runtime.ReadMemStats(&after)
+
+	r := Result{
+		Name:         s.Name,
+		Concurrency:  cfg.Concurrency,
+		Ops:          ops.Load(),
+		Elapsed:      elapsed,
+		AllocBytes:   after.TotalAlloc - before.TotalAlloc,
+		AllocObjects: after.Mallocs - before.Mallocs,
+		NumGC:        after.NumGC - before.NumGC,
+		PauseTotal:   time.Duration(after.PauseTotalNs - before.PauseTotalNs),
+		MaxPause:     maxPause(&after, before.NumGC),
+	}
+	r.OpsPerSec = float64(r.Ops) / elapsed.Seconds()
+	r.AllocBytesPerSec = float64(r.AllocBytes) / elapsed.Seconds()
+	if r.Ops > 0 {
+		r.BytesPerOp = float64(r.AllocBytes) / float64(r.Ops)
+		r.AllocsPerOp = float64(r.AllocObjects) / float64(r.Ops)
+	}
+	return r, nil
+}
+
+// Compare runs each scenario in turn with the same config.
+func Compare(cfg Config, scenarios ...Scenario) ([]Result, error) {
+	results := make([]Result, 0, len(scenarios))
+	for _, s := range scenarios {
+		r, err := Run(s, cfg)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", s.Name, err)
+		}
+		results = append(results, r)
+	}
+	return results, nil
+}
+
+// maxPause returns the longest pause among GC cycles completed after sinceGC.
+// MemStats keeps only the last 256 pauses, so older cycles are not considered.
+func maxPause(m *runtime.MemStats, sinceGC uint32) time.Duration {
+	n := m.NumGC - sinceGC
+	if n > uint32(len(m.PauseNs)) {
+		n = uint32(len(m.PauseNs))
+	}
+	var longest uint64
+	for i := uint32(0); i < n; i++ {
+		p := m.PauseNs[(m.NumGC-i+255)%256]
+		if p > longest {
+			longest = p
+		}
+	}
+	return time.Duration(longest)
+}
@@ -0,0 +1,54 @@
+package loadtest
+
+import (
+	"testing"
+	"time"
+)
+
+type slot struct {
+	p *[64]byte
+	_ [56]byte
+}
+
+func allocScenario(workers int) func(int, uint64) {
+	sinks := make([]slot, workers)
+	return func(worker int, i uint64) {
+		sinks[worker].p = new([64]byte)
+	}
+}
+
+func TestCompare(t *testing.T) {
+	cfg := Config{Concurrency: 2, Duration: 50 * time.Millisecond}
+	results, err := Compare(cfg,
+		Scenario{Name: "alloc", NewOp: allocScenario},
+		Scenario{Name: "noop", NewOp: func(int) func(int, uint64) { return func(int, uint64) {} }},
+	)
+	if err != nil {
+		t.Fatalf("Compare: %v", err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("results = %d, want 2", len(results))
+	}
+	alloc, noop := results[0], results[1]
+	if alloc.Ops == 0 || noop.Ops == 0 {
+		t.Fatalf("ops = %d/%d, want both > 0", alloc.Ops, noop.Ops)
+	}
+	if alloc.BytesPerOp < 32 {
+		t.Errorf("alloc BytesPerOp = %.1f, want >= 32", alloc.BytesPerOp)
+	}
+	if noop.BytesPerOp > 1 {
+		t.Errorf("noop BytesPerOp = %.1f, want ~0", noop.BytesPerOp)
+	}
+	if alloc.Concurrency != 2 || alloc.Elapsed < cfg.Duration {
+		t.Errorf("result config = %d workers over %s", alloc.Concurrency, alloc.Elapsed)
+	}
+}
+
+func TestConfigValidate(t *testing.T) {
+	if err := (Config{Concurrency: 0, Duration: time.Second}).Validate(); err == nil {
+		t.Error("zero concurrency accepted")
+	}
+	if err := (Config{Concurrency: 1}).Validate(); err == nil {
+		t.Error("zero duration accepted")
+	}
+}
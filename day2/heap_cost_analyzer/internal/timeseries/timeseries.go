@@ -0,0 +1,217 @@
+package timeseries
+
+import (
+	"math/rand/v2"
+	"runtime"
+	"sort"
+	"sync"
+	"time"
+)
+
+// maxSamplesPerInterval bounds the durations kept per interval for percentiles.
+// Beyond this, reservoir sampling keeps a uniform subset.
+const maxSamplesPerInterval = 1024
+
+// Point summarizes one interval of activity for an endpoint.
+// Heap and GC figures are process-wide and identical across endpoints for the same interval.
+type Point struct {
+	Time             time.Time     `json:"time"`
+	Requests         uint64        `json:"requests"`
+	RequestRate      float64       `json:"request_rate"`
+	P50              time.Duration `json:"p50_ns"`
+	P99              time.Duration `json:"p99_ns"`
+	AllocBytesPerSec float64       `json:"alloc_bytes_per_sec"`
+	NumGC            uint32        `json:"num_gc"`
+}
+
+// ring is a fixed-capacity buffer of points, oldest overwritten first.
+type ring struct {
+	points []Point
+	next   int
+	full   bool
+}
+
+func (r *ring) push(p Point) {
+	r.points[r.next] = p
+	r.next++
+	if r.next == len(r.points) {
+		r.next = 0
+		r.full = true
+	}
+}
+
+// ordered returns the points oldest first.
+func (r *ring) ordered() []Point {
+	if !r.full {
+		return append([]Point(nil), r.points[:r.next]...)
+	}
+	out := make([]Point, 0, len(r.points))
+	out = append(out, r.points[r.next:]...)
+	return append(out, r.points[:r.next]...)
+}
+
+type series struct {
+	ring    ring
+	count   uint64
+	samples []time.Duration
+}
+
+// Recorder keeps a ring-buffer time series per endpoint.
+// Observe is called from handlers; Tick closes the current interval.
+type Recorder struct {
+	mu       sync.Mutex
+	interval time.Duration
+	capacity int
+	series   map[string]*series
+	order    []string
+
+	lastTotalAlloc uint64
+	lastNumGC      uint32
+	lastTick       time.Time
+}
+
+// NewRecorder creates a recorder that keeps capacity points per endpoint,
+// one per interval.
+func NewRecorder(interval time.Duration, capacity int, endpoints ...string) *Recorder {
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+	r := &Recorder{
+		interval:       interval,
+		capacity:       capacity,
+		series:         make(map[string]*series),
+		lastTotalAlloc: m.TotalAlloc,
+		lastNumGC:      m.NumGC,
+		lastTick:       time.Now(),
+	}
+	for _, e := range endpoints {
+		r.seriesFor(e)
+	}
+	return r
+}
+
+// Interval returns the duration covered by each point.
+func (r *Recorder) Interval() time.Duration {
+	return r.interval
+}
+
+// seriesFor returns the series for endpoint, creating it if needed. Caller holds mu.
+func (r *Recorder) seriesFor(endpoint string) *series {
+	s, ok := r.series[endpoint]
+	if !ok {
+		s = &series{
+			ring:    ring{points: make([]Point, r.capacity)},
+			samples: make([]time.Duration, 0, maxSamplesPerInterval),
+		}
+		r.series[endpoint] = s
+		r.order = append(r.order, endpoint)
+	}
+	return s
+}
+
+// Observe records one handled request for endpoint.
+func (r *Recorder) Observe(endpoint string, d time.Duration) {
+	r.mu.Lock()
+	s := r.seriesFor(endpoint)
+	s.count++
+	if len(s.samples) < maxSamplesPerInterval {
+		s.samples = append(s.samples, d)
+	} else if i := rand.Uint64N(s.count); i < maxSamplesPerInterval {
+		s.samples[i] = d
+	}
+	r.mu.Unlock()
+}
+
+// Tick closes the current interval for every endpoint and appends a point.
+func (r *Recorder) Tick(now time.Time) {
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	elapsed := now.Sub(r.lastTick).Seconds()
+	if elapsed <= 0 {
+		elapsed = r.interval.Seconds()
+	}
+	allocRate := float64(m.TotalAlloc-r.lastTotalAlloc) / elapsed
+	numGC := m.NumGC - r.lastNumGC
+	r.lastTotalAlloc, r.lastNumGC, r.lastTick = m.TotalAlloc, m.NumGC, now
+
+	for _, s := range r.series {
+		p := Point{
+			Time:             now,
+			Requests:         s.count,
+			RequestRate:      float64(s.count) / elapsed,
+			AllocBytesPerSec: allocRate,
+			NumGC:            numGC,
+		}
+		p.P50, p.P99 = percentiles(s.samples)
+		s.ring.push(p)
+		s.count = 0
+		s.samples = s.samples[:0]
+	}
+}
+
+// Run calls Tick every interval until stop is closed.
+func (r *Recorder) Run(stop <-chan struct{}) {
+	t := time.NewTicker(r.interval)
+	defer t.Stop()
+	for {
+		select {
+		case now := <-t.C:
+			r.Tick(now)
+		case <-stop:
+			return
+		}
+	}
+}
+
+// Endpoints returns endpoint names in registration order.
+func (r *Recorder) Endpoints() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.order...)
+}
+
+// Range returns points for endpoint with from <= Time <= to, oldest first.
+// A zero from or to leaves that side unbounded.
+func (r *Recorder) Range(endpoint string, from, to time.Time) ([]Point, bool) {
+	r.mu.Lock()
+	s, ok := r.series[endpoint]
+	if !ok {
+		r.mu.Unlock()
+		return nil, false
+	}
+	all := s.ring.ordered()
+	r.mu.Unlock()
+
+	out := all[:0]
+	for _, p := range all {
+		if !from.IsZero() && p.Time.Before(from) {
+			continue
+		}
+		if !to.IsZero() && p.Time.After(to) {
+			continue
+		}
+		out = append(out, p)
+	}
+	return out, true
+}
+
+// Last returns the most recent n points for endpoint, oldest first.
+func (r *Recorder) Last(endpoint string, n int) []Point {
+	pts, _ := r.Range(endpoint, time.Time{}, time.Time{})
+	if len(pts) > n {
+		pts = pts[len(pts)-n:]
+	}
+	return pts
+}
+
+func percentiles(samples []time.Duration) (p50, p99 time.Duration) {
+	if len(samples) == 0 {
+		return 0, 0
+	}
+	sorted := append([]time.Duration(nil), samples...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	return sorted[(len(sorted)-1)*50/100], sorted[(len(sorted)-1)*99/100]
+}
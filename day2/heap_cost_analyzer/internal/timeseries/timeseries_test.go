@@ -0,0 +1,56 @@
+package timeseries
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRecorderTickAndRange(t *testing.T) {
+	r := NewRecorder(time.Second, 3, "heap", "stack")
+	base := time.Unix(1700000000, 0)
+
+	for i := 1; i <= 100; i++ {
+		r.Observe("heap", time.Duration(i)*time.Millisecond)
+	}
+	r.Tick(base)
+	r.Observe("stack", 5*time.Millisecond)
+	r.Tick(base.Add(time.Second))
+
+	heap, ok := r.Range("heap", time.Time{}, time.Time{})
+	if !ok || len(heap) != 2 {
+		t.Fatalf("heap points = %d (ok=%v), want 2", len(heap), ok)
+	}
+	if heap[0].Requests != 100 {
+		t.Errorf("Requests = %d, want 100", heap[0].Requests)
+	}
+	if heap[0].P50 != 50*time.Millisecond || heap[0].P99 != 99*time.Millisecond {
+		t.Errorf("p50/p99 = %s/%s, want 50ms/99ms", heap[0].P50, heap[0].P99)
+	}
+	if heap[1].Requests != 0 {
+		t.Errorf("second interval Requests = %d, want 0", heap[1].Requests)
+	}
+
+	stack, _ := r.Range("stack", base.Add(time.Second), time.Time{})
+	if len(stack) != 1 || stack[0].Requests != 1 {
+		t.Errorf("stack range = %+v, want one point with 1 request", stack)
+	}
+
+	if _, ok := r.Range("missing", time.Time{}, time.Time{}); ok {
+		t.Error("Range on unknown endpoint reported ok")
+	}
+}
+
+func TestRecorderRingOverwritesOldest(t *testing.T) {
+	r := NewRecorder(time.Second, 3, "heap")
+	base := time.Unix(1700000000, 0)
+	for i := 0; i < 5; i++ {
+		r.Tick(base.Add(time.Duration(i) * time.Second))
+	}
+	pts := r.Last("heap", 10)
+	if len(pts) != 3 {
+		t.Fatalf("points = %d, want 3", len(pts))
+	}
+	if !pts[0].Time.Equal(base.Add(2*time.Second)) || !pts[2].Time.Equal(base.Add(4*time.Second)) {
+		t.Errorf("ring order wrong: first=%s last=%s", pts[0].Time, pts[2].Time)
+	}
+}
@@ -0,0 +1,220 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"math"
+	"net/http"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync/atomic"
+)
+
+// admissionConfig bounds what the process handlers and load runs will take
+// on, so a single /naive?size=2000000000 cannot OOM a shared dev host.
+type admissionConfig struct {
+	// MaxSize is the largest ?size= accepted (400 above it).
+	MaxSize int64
+	// MaxBodyBytes is the largest POST body accepted (413 above it).
+	MaxBodyBytes int64
+	// MaxInFlight is how many process requests and load workers may run at
+	// once (429 above it).
+	MaxInFlight int64
+	// MaxInFlightBytes is the budget for the memory those hold at once: each
+	// call's processor.Footprint, or a body's length (503 above it).
+	MaxInFlightBytes int64
+}
+
+func defaultAdmissionConfig() admissionConfig {
+	return admissionConfig{
+		MaxSize:          16 << 20,
+		MaxBodyBytes:     8 << 20,
+		MaxInFlight:      int64(4 * runtime.GOMAXPROCS(0)),
+		MaxInFlightBytes: 256 << 20,
+	}
+}
+
+// admissionConfigFromEnv applies MAX_SIZE, MAX_BODY_BYTES, MAX_INFLIGHT and
+// MAX_INFLIGHT_BYTES over the defaults. Byte values take the same suffixes
+// as GOMEMLIMIT; bad values are logged and ignored.
+func admissionConfigFromEnv() admissionConfig {
+	cfg := defaultAdmissionConfig()
+	for _, v := range []struct {
+		env   string
+		dst   *int64
+		bytes bool
+	}{
+		{"MAX_SIZE", &cfg.MaxSize, true},
+		{"MAX_BODY_BYTES", &cfg.MaxBodyBytes, true},
+		{"MAX_INFLIGHT", &cfg.MaxInFlight, false},
+		{"MAX_INFLIGHT_BYTES", &cfg.MaxInFlightBytes, true},
+	} {
+		s := strings.TrimSpace(os.Getenv(v.env))
+		if s == "" {
+			continue
+		}
+		var n int64
+		var err error
+		if v.bytes {
+			n, err = parseMemoryLimit(s)
+		} else {
+			n, err = strconv.ParseInt(s, 10, 64)
+		}
+		if err != nil || n <= 0 || n == math.MaxInt64 {
+			slog.Warn("ignoring invalid admission limit", "env", v.env, "value", s)
+			continue
+		}
+		*v.dst = n
+	}
+	return cfg
+}
+
+// Rejection reasons, used as the reason label on the rejection counter.
+const (
+	rejectSize        = "size"
+	rejectBody        = "body"
+	rejectConcurrency = "concurrency"
+	rejectBytes       = "bytes"
+	rejectLoad        = "load"
+	rejectFootprint   = "footprint"
+)
+
+// admission tracks requests in flight against admissionConfig. Admission
+// never waits: a request that does not fit right now is turned away with
+// Retry-After, which keeps memory bounded even under a flood, and one that
+// could never fit is refused outright.
+type admission struct {
+	cfg      admissionConfig
+	inFlight atomic.Int64
+	bytes    atomic.Int64
+}
+
+func newAdmission(cfg admissionConfig) *admission {
+	return &admission{cfg: cfg}
+}
+
+var (
+	admit           = newAdmission(admissionConfigFromEnv())
+	rejectedCounter = promRegistry.NewCounter("http_requests_rejected_total",
+		"Process requests turned away by validation or admission control, by handler and reason.", "handler", "reason")
+)
+
+func init() {
+	promRegistry.NewGaugeFunc("process_requests_in_flight", "Process requests and load workers currently admitted.",
+		func() float64 { return float64(admit.inFlight.Load()) })
+	promRegistry.NewGaugeFunc("process_bytes_in_flight", "Memory reserved for admitted process requests and load workers, in bytes.",
+		func() float64 { return float64(admit.bytes.Load()) })
+}
+
+// tryAcquire reserves slots and n bytes. On success it returns a release
+// func; otherwise it returns the rejection reason.
+func (a *admission) tryAcquire(slots, n int64) (release func(), reason string) {
+	if a.inFlight.Add(slots) > a.cfg.MaxInFlight {
+		a.inFlight.Add(-slots)
+		return nil, rejectConcurrency
+	}
+	if a.bytes.Add(n) > a.cfg.MaxInFlightBytes {
+		a.bytes.Add(-n)
+		a.inFlight.Add(-slots)
+		return nil, rejectBytes
+	}
+	return func() {
+		a.bytes.Add(-n)
+		a.inFlight.Add(-slots)
+	}, ""
+}
+
+// reject answers a refused request and counts it under the processor name,
+// whether it came in through /process/{name} or /<name>, or under the path
+// for load runs.
+func reject(w http.ResponseWriter, r *http.Request, reason string, msg string) {
+	handler := r.PathValue("name")
+	if handler == "" {
+		handler = strings.TrimPrefix(r.URL.Path, "/")
+	}
+	rejectedCounter.With(handler, reason).Inc()
+	status := http.StatusBadRequest
+	switch reason {
+	case rejectBody, rejectFootprint:
+		status = http.StatusRequestEntityTooLarge
+	case rejectConcurrency:
+		status = http.StatusTooManyRequests
+	case rejectBytes:
+		status = http.StatusServiceUnavailable
+	}
+	if status == http.StatusTooManyRequests || status == http.StatusServiceUnavailable {
+		w.Header().Set("Retry-After", "1")
+	}
+	http.Error(w, msg, status)
+}
+
+// admitSize validates size and admits a call reserving footprint bytes,
+// the processor's estimate for that size; ok is false if the request was
+// rejected (and answered).
+func (a *admission) admitSize(w http.ResponseWriter, r *http.Request, size, footprint int64) (release func(), ok bool) {
+	if size > a.cfg.MaxSize {
+		reject(w, r, rejectSize, fmt.Sprintf("'size' exceeds the maximum of %d", a.cfg.MaxSize))
+		return nil, false
+	}
+	if !a.fits(w, r, footprint) {
+		return nil, false
+	}
+	return a.admit(w, r, 1, footprint)
+}
+
+// admitBody admits a POST body, reserving its Content-Length, or the full
+// body limit when the length is unknown.
+func (a *admission) admitBody(w http.ResponseWriter, r *http.Request) (release func(), ok bool) {
+	if r.ContentLength > a.cfg.MaxBodyBytes {
+		reject(w, r, rejectBody, fmt.Sprintf("request body over %d bytes", a.cfg.MaxBodyBytes))
+		return nil, false
+	}
+	n := r.ContentLength
+	if n < 0 {
+		n = a.cfg.MaxBodyBytes
+	}
+	if !a.fits(w, r, n) {
+		return nil, false
+	}
+	return a.admit(w, r, 1, n)
+}
+
+// fits rejects a call whose reservation alone exceeds MaxInFlightBytes
+// with 413 and no Retry-After: it could never be admitted, so a retry
+// cannot help.
+func (a *admission) fits(w http.ResponseWriter, r *http.Request, n int64) bool {
+	if n > a.cfg.MaxInFlightBytes {
+		reject(w, r, rejectFootprint, fmt.Sprintf("this call needs about %d bytes, over the in-flight budget of %d", n, a.cfg.MaxInFlightBytes))
+		return false
+	}
+	return true
+}
+
+// admitLoad admits a load run for its whole duration: a slot per worker
+// and footprint bytes for each, so load runs and process requests share
+// one budget. A run larger than the limits themselves gets 400, since
+// retrying cannot help.
+func (a *admission) admitLoad(w http.ResponseWriter, r *http.Request, workers int, footprint int64) (release func(), ok bool) {
+	n := int64(workers) * footprint
+	if int64(workers) > a.cfg.MaxInFlight || n > a.cfg.MaxInFlightBytes {
+		reject(w, r, rejectLoad, fmt.Sprintf("%d workers holding %d bytes each exceed the admission limits (%d in flight, %d bytes)",
+			workers, footprint, a.cfg.MaxInFlight, a.cfg.MaxInFlightBytes))
+		return nil, false
+	}
+	return a.admit(w, r, int64(workers), n)
+}
+
+func (a *admission) admit(w http.ResponseWriter, r *http.Request, slots, n int64) (func(), bool) {
+	release, reason := a.tryAcquire(slots, n)
+	switch reason {
+	case rejectConcurrency:
+		reject(w, r, reason, fmt.Sprintf("too many requests in flight (max %d); retry shortly", a.cfg.MaxInFlight))
+		return nil, false
+	case rejectBytes:
+		reject(w, r, reason, fmt.Sprintf("in-flight byte budget of %d exhausted; retry shortly", a.cfg.MaxInFlightBytes))
+		return nil, false
+	}
+	return release, true
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"gc_hidden_cost/processor"
+	"lessonkit/tracing"
+)
+
+// handleBody serves POST requests: the processor reads the request body,
+// upper-cases it and writes it back. Bodies over the admission limit get
+// 413, before reading when Content-Length already says so.
+func handleBody(w http.ResponseWriter, r *http.Request, p processor.Processor, recordStats func(time.Duration)) {
+	bp, ok := p.(processor.BodyProcessor)
+	if !ok {
+		w.Header().Set("Allow", "GET")
+		http.Error(w, fmt.Sprintf("%T does not accept request bodies", p), http.StatusMethodNotAllowed)
+		return
+	}
+	release, ok := admit.admitBody(w, r)
+	if !ok {
+		return
+	}
+	defer release()
+
+	ctx, span := tracing.Start(r.Context(), "process.body")
+	defer span.End()
+	span.SetAttr("content_length", strconv.FormatInt(r.ContentLength, 10))
+	// The processor writes only after the whole body is read and
+	// transformed, so errors below can still set the status.
+	w.Header().Set("Content-Type", "application/octet-stream")
+	start := time.Now()
+	_, err := bp.ProcessBody(ctx, w, r.Body, r.ContentLength, admit.cfg.MaxBodyBytes)
+	duration := time.Since(start)
+	if err != nil {
+		span.SetError(err.Error())
+		if errors.Is(err, processor.ErrBodyTooLarge) {
+			reject(w, r, rejectBody, fmt.Sprintf("request body over %d bytes", admit.cfg.MaxBodyBytes))
+			return
+		}
+		http.Error(w, fmt.Sprintf("processing error: %v", err), http.StatusBadRequest)
+		return
+	}
+	if recordStats != nil {
+		recordStats(duration)
+	}
+}
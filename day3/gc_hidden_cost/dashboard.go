@@ -0,0 +1,221 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"runtime"
+	"time"
+
+	"gc_hidden_cost/processor"
+	"lessonkit/dashboard"
+)
+
+var dash = newDashboard()
+
+func newDashboard() *dashboard.Dashboard {
+	d := dashboard.New("GC Hidden Cost Dashboard", dashboard.Light)
+	d.Intro = "This app compares two ways of handling per-request buffers in Go: Naive (new allocation every time) vs Pooled (sync.Pool reuse). Use the actions below to generate load; watch GC metrics and live request stats to see how pooling reduces allocations and GC pressure."
+	d.Nav = []dashboard.Link{
+		{Label: "Dashboard", URL: "/"},
+		{Label: "JSON MemStats", URL: "/debug/mem"},
+		{Label: "JSON GC Debug", URL: "/debug/gc"},
+		{Label: "JSON Live Stats", URL: "/api/stats"},
+		{Label: "JSON Experiments", URL: "/experiment/results"},
+		{Label: "Compare with hey runs", URL: "/experiment/compare"},
+		{Label: "Prometheus", URL: "/metrics"},
+		{Label: "Event Stream", URL: "/events"},
+	}
+	d.EventsPath = "/events"
+	d.Add(dashboard.Panel{ID: "processors", Title: "Processors", Render: processorPanel})
+	d.Add(dashboard.Panel{ID: "actions", Static: true, Render: func() []dashboard.Block {
+		return []dashboard.Block{dashboard.Links{
+			{Label: "Run Naive (64KB)", URL: "/process/naive?size=65536"},
+			{Label: "Run Pooled (64KB)", URL: "/process/pooled?size=65536"},
+		}, processorActions()}
+	}})
+	d.Add(dashboard.Panel{ID: "pool", Title: "Buffer pool reuse", Render: poolPanel})
+	d.Add(dashboard.Panel{ID: "experiments", Title: "Load experiments", Render: experimentPanel})
+	d.Add(dashboard.Panel{ID: "experiment-controls", Static: true, Render: experimentControls})
+	d.Add(dashboard.Panel{ID: "gc", Title: "GC & memory metrics", Render: memStatsPanel})
+	d.Add(dashboard.Panel{ID: "pauses", Title: "GC pause distribution", Render: gcPausePanel})
+	d.Add(dashboard.Panel{ID: "gc-settings", Title: "Collector settings", Render: gcSettingsPanel})
+	d.Add(dashboard.Panel{ID: "gc-controls", Static: true, Render: gcControls})
+	return d
+}
+
+func dashboardHandler(w http.ResponseWriter, r *http.Request) {
+	dash.ServeHTTP(w, r)
+}
+
+// poolPanel shows whether the pooled processor's buffers are being reused.
+func poolPanel() []dashboard.Block {
+	pooled, ok := registry.Lookup("pooled")
+	if !ok {
+		return nil
+	}
+	reporter, ok := pooled.proc.(processor.PoolReporter)
+	if !ok {
+		return nil
+	}
+	st := reporter.Stats()
+	table := &dashboard.Table{Columns: []string{"Class", "Hits", "New calls", "Puts"}}
+	for _, c := range st.Classes {
+		if c.Hits+c.Misses+c.Puts == 0 {
+			continue
+		}
+		table.Rows = append(table.Rows, []any{fmt.Sprintf("%d KB", c.Size>>10), c.Hits, c.Misses, c.Puts})
+	}
+	blocks := []dashboard.Block{dashboard.Cards{
+		{Label: "Reuse rate", Value: fmt.Sprintf("%.1f%%", 100*st.ReuseRate()), Live: true},
+		{Label: "Get hits", Value: st.Hits, Live: true},
+		{Label: "New calls", Value: st.Misses, Live: true},
+		{Label: "Puts", Value: st.Puts, Live: true},
+		{Label: "Oversize (not pooled)", Value: st.Oversize},
+		{Label: "Discarded puts", Value: st.Discards},
+	}}
+	if len(table.Rows) > 0 {
+		blocks = append(blocks, table)
+	}
+	return blocks
+}
+
+func memStatsPanel() []dashboard.Block {
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+	return []dashboard.Block{dashboard.Cards{
+		{Label: "Alloc (bytes)", Value: m.Alloc},
+		{Label: "TotalAlloc (bytes)", Value: m.TotalAlloc},
+		{Label: "Sys (bytes)", Value: m.Sys},
+		{Label: "NumGC", Value: m.NumGC},
+		{Label: "GCCPUFraction", Value: m.GCCPUFraction},
+		{Label: "PauseTotalNs", Value: m.PauseTotalNs},
+		{Label: "HeapObjects", Value: m.HeapObjects},
+		{Label: "LastGC", Value: time.Unix(0, int64(m.LastGC)).Format(time.RFC3339Nano)},
+	}}
+}
+
+func durationOrDash(ns int64) string {
+	if ns <= 0 {
+		return "—"
+	}
+	return time.Duration(ns).String()
+}
+
+// gcPausePanel summarizes the /debug/gc histograms and GC CPU breakdown.
+func gcPausePanel() []dashboard.Block {
+	rep := readGCDebug(0)
+	table := &dashboard.Table{Columns: []string{"Histogram", "Count", "p50", "p90", "p99", "Max"}}
+	for _, name := range gcHistogramMetrics {
+		h, ok := rep.Histograms[name]
+		if !ok {
+			continue
+		}
+		table.Rows = append(table.Rows, []any{name, h.Count,
+			secondsDuration(h.P50), secondsDuration(h.P90), secondsDuration(h.P99), secondsDuration(h.Max)})
+	}
+	return []dashboard.Block{table, dashboard.Cards{
+		{Label: "Heap goal (bytes)", Value: rep.HeapGoalBytes},
+		{Label: "GC CPU (assist / dedicated / idle / pause)", Value: fmt.Sprintf("%.3fs / %.3fs / %.3fs / %.3fs",
+			rep.CPU.AssistSeconds, rep.CPU.DedicatedSeconds, rep.CPU.IdleSeconds, rep.CPU.PauseSeconds)},
+		{Label: "GC share of CPU", Value: fmt.Sprintf("%.2f%%", 100*rep.CPU.GCFraction)},
+	}}
+}
+
+func secondsDuration(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
+
+func gcSettingsPanel() []dashboard.Block {
+	cur := activeGCSettings()
+	cards := dashboard.Cards{
+		{Label: "GOGC", Value: formatGOGC(cur.GOGC), Live: true},
+		{Label: "GOMEMLIMIT", Value: formatMemoryLimit(cur.MemoryLimit), Live: true},
+	}
+	for _, name := range headlineProcessors {
+		if p, ok := registry.Lookup(name); ok {
+			cards = append(cards, dashboard.Card{Label: "Last " + name + " request ran with", Value: p.lastGCSettings().String()})
+		}
+	}
+	return []dashboard.Block{cards}
+}
+
+// gcControls changes the collector settings and starts a GOGC sweep.
+func gcControls() []dashboard.Block {
+	return []dashboard.Block{
+		&dashboard.Form{Action: "/admin/gc", Submit: "Apply", Fields: []dashboard.Field{
+			{Name: "gogc", Label: "GOGC", Placeholder: "100 or off"},
+			{Name: "gomemlimit", Label: "GOMEMLIMIT", Placeholder: "512MiB or off"},
+			{Name: "redirect", Value: "/", Hidden: true},
+		}},
+		&dashboard.Form{Action: "/experiment/gogc", Submit: "Run GOGC sweep", Fields: []dashboard.Field{
+			{Name: "values", Label: "GOGC values", Value: "25,50,100,200,400"},
+			{Name: "size", Label: "Size", Value: "65536"},
+			{Name: "concurrency", Label: "Concurrency", Value: fmt.Sprint(runtime.GOMAXPROCS(0))},
+			{Name: "duration", Label: "Per run", Value: "1s"},
+		}},
+	}
+}
+
+// experimentPanel shows the latest experiment with one column per processor
+// and links to earlier results.
+func experimentPanel() []dashboard.Block {
+	recent := experiments.Recent()
+	if len(recent) == 0 {
+		return []dashboard.Block{dashboard.Text("No experiments yet. Run one below to compare processors under identical load.")}
+	}
+	e := recent[0]
+	table := &dashboard.Table{Columns: []string{"Metric"}}
+	rows := []struct {
+		label string
+		value func(loadResult) any
+	}{
+		{"Call", func(r loadResult) any { return r.callMode() }},
+		{"Ops/s", func(r loadResult) any { return fmt.Sprintf("%.0f", r.OpsPerSec) }},
+		{"p50", func(r loadResult) any { return time.Duration(r.P50Ns) }},
+		{"p90", func(r loadResult) any { return time.Duration(r.P90Ns) }},
+		{"p99", func(r loadResult) any { return time.Duration(r.P99Ns) }},
+		{"Max", func(r loadResult) any { return time.Duration(r.MaxNs) }},
+		{"Alloc rate", func(r loadResult) any { return mib(uint64(r.AllocBytesPerS)) + "/s" }},
+		{"Mallocs/op", func(r loadResult) any { return fmt.Sprintf("%.2f", r.MallocsPerOp) }},
+		{"GC cycles", func(r loadResult) any { return r.NumGC }},
+		{"GC pause total", func(r loadResult) any { return time.Duration(r.PauseTotalNs) }},
+		{"Peak heap", func(r loadResult) any { return mib(r.PeakHeapBytes) }},
+		{"Errors", func(r loadResult) any { return r.Errors }},
+		{"Pool reuse", func(r loadResult) any {
+			if r.Pool == nil {
+				return "—"
+			}
+			return fmt.Sprintf("%.1f%%", 100*r.Pool.ReuseRate())
+		}},
+	}
+	for _, run := range e.Runs {
+		table.Columns = append(table.Columns, run.Processor)
+	}
+	for _, row := range rows {
+		cells := []any{row.label}
+		for _, run := range e.Runs {
+			cells = append(cells, row.value(run))
+		}
+		table.Rows = append(table.Rows, cells)
+	}
+	history := dashboard.Links{}
+	for _, old := range recent {
+		history = append(history, dashboard.Link{Label: old.ID, URL: "/experiment/results/" + old.ID, NewTab: true})
+	}
+	return []dashboard.Block{
+		dashboard.Text(fmt.Sprintf("Experiment %s: %d bytes per call, %d goroutines, %s per processor, %s.",
+			e.ID, e.Size, e.Concurrency, time.Duration(e.DurationNs), e.GCSettings)),
+		table,
+		history,
+	}
+}
+
+func experimentControls() []dashboard.Block {
+	return []dashboard.Block{&dashboard.Form{Action: "/experiment/run", Submit: "Run experiment", Fields: []dashboard.Field{
+		{Name: "processors", Label: "Processors", Value: "naive,pooled"},
+		{Name: "size", Label: "Size", Value: "65536"},
+		{Name: "concurrency", Label: "Concurrency", Value: fmt.Sprint(runtime.GOMAXPROCS(0))},
+		{Name: "duration", Label: "Per processor", Value: "2s"},
+		{Name: "redirect", Value: "/", Hidden: true},
+	}}}
+}
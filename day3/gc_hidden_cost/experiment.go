@@ -0,0 +1,263 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"gc_hidden_cost/processor"
+)
+
+// maxRecentExperiments bounds how many stored results are kept in memory
+// for the dashboard and /experiment/results; older files stay on disk.
+const maxRecentExperiments = 20
+
+// maxExperimentProcessors caps the processors one experiment runs in turn;
+// at maxSweepDuration each, a run stays within maxSweepTotal.
+const maxExperimentProcessors = 8
+
+// experiment is one stored comparison: the same load applied to each
+// processor in turn, replacing hand-saved hey output files.
+type experiment struct {
+	ID          string       `json:"id"`
+	Started     time.Time    `json:"started"`
+	Size        int          `json:"size"`
+	Concurrency int          `json:"concurrency"`
+	DurationNs  int64        `json:"duration_ns"`
+	GCSettings  gcSettings   `json:"gc_settings"`
+	GoVersion   string       `json:"go_version"`
+	GOMAXPROCS  int          `json:"gomaxprocs"`
+	Runs        []loadResult `json:"runs"`
+}
+
+// experimentStore persists experiments as <dir>/<id>.json and keeps the
+// most recent ones in memory, newest first.
+type experimentStore struct {
+	dir    string
+	mu     sync.Mutex
+	loaded bool
+	recent []experiment
+}
+
+func newExperimentStore(dir string) *experimentStore {
+	return &experimentStore{dir: dir}
+}
+
+// experiments is where the runner saves results; EXPERIMENT_DIR overrides
+// the default ./experiments, which is gitignored.
+var experiments = newExperimentStore(experimentDir())
+
+func experimentDir() string {
+	if dir := os.Getenv("EXPERIMENT_DIR"); dir != "" {
+		return dir
+	}
+	return "experiments"
+}
+
+// Save writes e to disk and makes it the latest result.
+func (s *experimentStore) Save(e experiment) error {
+	data, err := json.MarshalIndent(e, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(s.dir, 0o755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(s.dir, e.ID+".json"), append(data, '\n'), 0o644); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.loadLocked()
+	s.recent = append([]experiment{e}, s.recent...)
+	if len(s.recent) > maxRecentExperiments {
+		s.recent = s.recent[:maxRecentExperiments]
+	}
+	return nil
+}
+
+// Recent returns up to maxRecentExperiments results, newest first, reading
+// the directory on first use. Unreadable files are logged and skipped.
+func (s *experimentStore) Recent() []experiment {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.loadLocked()
+	return append([]experiment(nil), s.recent...)
+}
+
+// Get returns a stored experiment by ID, including ones no longer recent.
+func (s *experimentStore) Get(id string) (experiment, error) {
+	var e experiment
+	if !validExperimentID(id) {
+		return e, fs.ErrNotExist
+	}
+	data, err := os.ReadFile(filepath.Join(s.dir, id+".json"))
+	if err != nil {
+		return e, err
+	}
+	err = json.Unmarshal(data, &e)
+	return e, err
+}
+
+func (s *experimentStore) loadLocked() {
+	if s.loaded {
+		return
+	}
+	s.loaded = true
+	paths, _ := filepath.Glob(filepath.Join(s.dir, "*.json"))
+	// IDs are timestamps, so lexical order is chronological.
+	sort.Sort(sort.Reverse(sort.StringSlice(paths)))
+	for _, path := range paths {
+		if len(s.recent) == maxRecentExperiments {
+			break
+		}
+		var e experiment
+		data, err := os.ReadFile(path)
+		if err == nil {
+			err = json.Unmarshal(data, &e)
+		}
+		if err != nil {
+			slog.Warn("skipping unreadable experiment", "path", path, "err", err)
+			continue
+		}
+		s.recent = append(s.recent, e)
+	}
+}
+
+func newExperimentID(t time.Time) string {
+	return t.UTC().Format("20060102T150405.000Z")
+}
+
+// validExperimentID keeps IDs from naming anything outside the store.
+func validExperimentID(id string) bool {
+	if id == "" {
+		return false
+	}
+	for _, c := range id {
+		if !(c >= '0' && c <= '9' || c == 'T' || c == 'Z' || c == '.') {
+			return false
+		}
+	}
+	return true
+}
+
+// runExperiment drives each named processor in turn with the same load.
+func runExperiment(names []string, size, concurrency int, d time.Duration) experiment {
+	start := time.Now()
+	e := experiment{
+		ID:          newExperimentID(start),
+		Started:     start,
+		Size:        size,
+		Concurrency: concurrency,
+		DurationNs:  d.Nanoseconds(),
+		GCSettings:  activeGCSettings(),
+		GoVersion:   runtime.Version(),
+		GOMAXPROCS:  runtime.GOMAXPROCS(0),
+	}
+	for _, name := range names {
+		// Each run gets a fresh instance so a pool warmed by one run cannot
+		// help the next.
+		p, _ := registry.Lookup(name)
+		e.Runs = append(e.Runs, runProcessorLoad(name, p.new(), size, concurrency, d))
+	}
+	return e
+}
+
+// handleExperimentRun (POST) runs an experiment and stores the result.
+// Form values: processors (comma list, default naive,pooled), size,
+// concurrency, duration (per processor, default 2s). A redirect value
+// answers with 303, as for /admin/gc; otherwise the result is returned
+// as JSON with its location.
+func handleExperimentRun(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", "POST")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	names := []string{"naive", "pooled"}
+	if s := strings.TrimSpace(r.Form.Get("processors")); s != "" {
+		names = names[:0]
+		for _, name := range strings.Split(s, ",") {
+			name = strings.TrimSpace(name)
+			if _, ok := registry.Lookup(name); !ok {
+				http.Error(w, fmt.Sprintf("unknown processor %q", name), http.StatusBadRequest)
+				return
+			}
+			names = append(names, name)
+		}
+	}
+	var procs []processor.Processor
+	for _, name := range names {
+		p, _ := registry.Lookup(name)
+		procs = append(procs, p.proc)
+	}
+	if len(names) > maxExperimentProcessors {
+		http.Error(w, fmt.Sprintf("at most %d processors per experiment", maxExperimentProcessors), http.StatusBadRequest)
+		return
+	}
+	size, concurrency, duration, err := parseLoadParams(r.Form, 2*time.Second)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if !loadRunning.CompareAndSwap(false, true) {
+		http.Error(w, "a load experiment is already running", http.StatusConflict)
+		return
+	}
+	defer loadRunning.Store(false)
+	// Processors run one at a time, so the largest footprint is the peak.
+	release, ok := admit.admitLoad(w, r, concurrency, loadFootprint(size, procs...))
+	if !ok {
+		return
+	}
+	defer release()
+	e := runExperiment(names, size, concurrency, duration)
+
+	if err := experiments.Save(e); err != nil {
+		http.Error(w, fmt.Sprintf("saving experiment: %v", err), http.StatusInternalServerError)
+		return
+	}
+	if to := r.Form.Get("redirect"); strings.HasPrefix(to, "/") && !strings.HasPrefix(to, "//") {
+		http.Redirect(w, r, to, http.StatusSeeOther)
+		return
+	}
+	w.Header().Set("Location", "/experiment/results/"+e.ID)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(e)
+}
+
+// handleExperimentResults lists recent experiments, newest first.
+func handleExperimentResults(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(experiments.Recent())
+}
+
+// handleExperimentResult returns one stored experiment.
+func handleExperimentResult(w http.ResponseWriter, r *http.Request) {
+	e, err := experiments.Get(r.PathValue("id"))
+	if errors.Is(err, fs.ErrNotExist) {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(e)
+}
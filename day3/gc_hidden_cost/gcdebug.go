@@ -0,0 +1,206 @@
+package main
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"runtime"
+	rtmetrics "runtime/metrics"
+	"strconv"
+
+	"lessonkit/dashboard"
+)
+
+// Histograms reported by /debug/gc. /gc/pauses:seconds is the classic GC
+// pause distribution; /sched/pauses/total/gc:seconds is its newer, more
+// precise stop-the-world replacement and is included when the runtime has it.
+var gcHistogramMetrics = []string{
+	"/gc/pauses:seconds",
+	"/sched/pauses/total/gc:seconds",
+	"/sched/latencies:seconds",
+}
+
+var gcScalarMetrics = []string{
+	"/gc/heap/goal:bytes",
+	"/gc/heap/live:bytes",
+	"/gc/gogc:percent",
+	"/gc/gomemlimit:bytes",
+	"/gc/cycles/automatic:gc-cycles",
+	"/gc/cycles/forced:gc-cycles",
+	"/gc/cycles/total:gc-cycles",
+	"/cpu/classes/gc/mark/assist:cpu-seconds",
+	"/cpu/classes/gc/mark/dedicated:cpu-seconds",
+	"/cpu/classes/gc/mark/idle:cpu-seconds",
+	"/cpu/classes/gc/pause:cpu-seconds",
+	"/cpu/classes/gc/total:cpu-seconds",
+	"/cpu/classes/total:cpu-seconds",
+}
+
+// histogramBucket is one non-empty bucket. Upper is omitted for the
+// open-ended last bucket, since JSON cannot carry +Inf.
+type histogramBucket struct {
+	Lower float64  `json:"lower_seconds"`
+	Upper *float64 `json:"upper_seconds,omitempty"`
+	Count uint64   `json:"count"`
+}
+
+type histogramReport struct {
+	Count   uint64            `json:"count"`
+	P50     float64           `json:"p50_seconds"`
+	P90     float64           `json:"p90_seconds"`
+	P99     float64           `json:"p99_seconds"`
+	Max     float64           `json:"max_seconds"`
+	Buckets []histogramBucket `json:"buckets"`
+}
+
+type gcCPUReport struct {
+	AssistSeconds    float64 `json:"mark_assist_seconds"`
+	DedicatedSeconds float64 `json:"mark_dedicated_seconds"`
+	IdleSeconds      float64 `json:"mark_idle_seconds"`
+	PauseSeconds     float64 `json:"pause_seconds"`
+	GCTotalSeconds   float64 `json:"gc_total_seconds"`
+	TotalSeconds     float64 `json:"total_seconds"`
+	// GCFraction is GCTotalSeconds / TotalSeconds since process start.
+	GCFraction float64 `json:"gc_fraction"`
+}
+
+type gcDebugReport struct {
+	NumGC         uint32                     `json:"num_gc"`
+	ForcedGC      uint32                     `json:"forced_gc"`
+	HeapGoalBytes uint64                     `json:"heap_goal_bytes"`
+	HeapLiveBytes uint64                     `json:"heap_live_bytes"`
+	NextGCBytes   uint64                     `json:"next_gc_bytes"`
+	GOGC          uint64                     `json:"gogc_percent"`
+	MemoryLimit   uint64                     `json:"gomemlimit_bytes"`
+	GCCPUFraction float64                    `json:"gc_cpu_fraction"`
+	CPU           gcCPUReport                `json:"cpu"`
+	Histograms    map[string]histogramReport `json:"histograms"`
+	// Cycles holds the most recent (up to 256) per-cycle pauses from
+	// MemStats.PauseNs, oldest first.
+	Cycles []dashboard.GCCycle `json:"cycles"`
+}
+
+// handleGCDebug reports the GC pause and scheduler latency distributions,
+// recent per-cycle pauses, the heap goal and the GC CPU breakdown.
+// ?cycles=N limits the per-cycle list to the last N entries.
+func handleGCDebug(w http.ResponseWriter, r *http.Request) {
+	limit := len(runtime.MemStats{}.PauseNs)
+	if s := r.URL.Query().Get("cycles"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 0 || n > limit {
+			http.Error(w, "invalid 'cycles' parameter (0-256)", http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(readGCDebug(limit))
+}
+
+func readGCDebug(cycleLimit int) gcDebugReport {
+	names := append(append([]string(nil), gcHistogramMetrics...), gcScalarMetrics...)
+	samples := make([]rtmetrics.Sample, len(names))
+	for i, name := range names {
+		samples[i].Name = name
+	}
+	rtmetrics.Read(samples)
+	vals := make(map[string]rtmetrics.Value, len(samples))
+	for _, s := range samples {
+		vals[s.Name] = s.Value
+	}
+
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+
+	rep := gcDebugReport{
+		NumGC:         m.NumGC,
+		ForcedGC:      m.NumForcedGC,
+		HeapGoalBytes: uint64Value(vals["/gc/heap/goal:bytes"]),
+		HeapLiveBytes: uint64Value(vals["/gc/heap/live:bytes"]),
+		NextGCBytes:   m.NextGC,
+		GOGC:          uint64Value(vals["/gc/gogc:percent"]),
+		MemoryLimit:   uint64Value(vals["/gc/gomemlimit:bytes"]),
+		GCCPUFraction: m.GCCPUFraction,
+		CPU: gcCPUReport{
+			AssistSeconds:    floatValue(vals["/cpu/classes/gc/mark/assist:cpu-seconds"]),
+			DedicatedSeconds: floatValue(vals["/cpu/classes/gc/mark/dedicated:cpu-seconds"]),
+			IdleSeconds:      floatValue(vals["/cpu/classes/gc/mark/idle:cpu-seconds"]),
+			PauseSeconds:     floatValue(vals["/cpu/classes/gc/pause:cpu-seconds"]),
+			GCTotalSeconds:   floatValue(vals["/cpu/classes/gc/total:cpu-seconds"]),
+			TotalSeconds:     floatValue(vals["/cpu/classes/total:cpu-seconds"]),
+		},
+		Histograms: map[string]histogramReport{},
+		Cycles:     recentCycles(&m, cycleLimit),
+	}
+	if rep.CPU.TotalSeconds > 0 {
+		rep.CPU.GCFraction = rep.CPU.GCTotalSeconds / rep.CPU.TotalSeconds
+	}
+	for _, name := range gcHistogramMetrics {
+		if v := vals[name]; v.Kind() == rtmetrics.KindFloat64Histogram {
+			rep.Histograms[name] = summarizeHistogram(v.Float64Histogram())
+		}
+	}
+	return rep
+}
+
+// recentCycles returns up to limit of the most recent cycles, oldest first,
+// and an empty (not nil) list before the first cycle.
+func recentCycles(m *runtime.MemStats, limit int) []dashboard.GCCycle {
+	if cycles := dashboard.GCCyclesSince(m.NumGC-min(m.NumGC, uint32(limit)), m); cycles != nil {
+		return cycles
+	}
+	return []dashboard.GCCycle{}
+}
+
+// summarizeHistogram keeps non-empty buckets and estimates percentiles as the
+// upper bound of the bucket containing each rank, matching how Prometheus
+// would interpret the same buckets.
+func summarizeHistogram(h *rtmetrics.Float64Histogram) histogramReport {
+	var rep histogramReport
+	for _, c := range h.Counts {
+		rep.Count += c
+	}
+	targets := []struct {
+		q   float64
+		dst *float64
+	}{{0.50, &rep.P50}, {0.90, &rep.P90}, {0.99, &rep.P99}, {1, &rep.Max}}
+	var seen uint64
+	for i, c := range h.Counts {
+		if c == 0 {
+			continue
+		}
+		lower, upper := h.Buckets[i], h.Buckets[i+1]
+		b := histogramBucket{Lower: math.Max(lower, 0), Count: c}
+		if !math.IsInf(upper, 1) {
+			u := upper
+			b.Upper = &u
+		}
+		rep.Buckets = append(rep.Buckets, b)
+
+		seen += c
+		bound := upper
+		if math.IsInf(bound, 1) {
+			bound = lower
+		}
+		for _, t := range targets {
+			if *t.dst == 0 && float64(seen) >= t.q*float64(rep.Count) {
+				*t.dst = bound
+			}
+		}
+	}
+	return rep
+}
+
+func uint64Value(v rtmetrics.Value) uint64 {
+	if v.Kind() == rtmetrics.KindUint64 {
+		return v.Uint64()
+	}
+	return 0
+}
+
+func floatValue(v rtmetrics.Value) float64 {
+	if v.Kind() == rtmetrics.KindFloat64 {
+		return v.Float64()
+	}
+	return 0
+}
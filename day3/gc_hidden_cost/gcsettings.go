@@ -0,0 +1,183 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// gcSettings is the collector configuration in effect for a request.
+type gcSettings struct {
+	// GOGC is the heap growth percentage, or -1 when the collector is off.
+	GOGC int `json:"gogc"`
+	// MemoryLimit is the soft memory limit in bytes; math.MaxInt64 means none.
+	MemoryLimit int64 `json:"gomemlimit"`
+}
+
+func (s gcSettings) String() string {
+	return fmt.Sprintf("GOGC=%s GOMEMLIMIT=%s", formatGOGC(s.GOGC), formatMemoryLimit(s.MemoryLimit))
+}
+
+var (
+	// gcSettingsMu serializes changes; currentGC lets request handlers read
+	// the active settings without touching the runtime.
+	gcSettingsMu sync.Mutex
+	currentGC    atomic.Pointer[gcSettings]
+	// loadRunning is set while a sweep or experiment drives load. It rejects
+	// overlapping runs and manual changes that would skew their numbers.
+	loadRunning atomic.Bool
+)
+
+func init() {
+	gcSettingsMu.Lock()
+	defer gcSettingsMu.Unlock()
+	pct := debug.SetGCPercent(100)
+	debug.SetGCPercent(pct)
+	currentGC.Store(&gcSettings{GOGC: pct, MemoryLimit: debug.SetMemoryLimit(-1)})
+}
+
+func activeGCSettings() gcSettings {
+	return *currentGC.Load()
+}
+
+// applyGCSettings installs s and returns the settings it replaced.
+func applyGCSettings(s gcSettings) gcSettings {
+	gcSettingsMu.Lock()
+	defer gcSettingsMu.Unlock()
+	prev := gcSettings{GOGC: debug.SetGCPercent(s.GOGC), MemoryLimit: debug.SetMemoryLimit(s.MemoryLimit)}
+	currentGC.Store(&s)
+	return prev
+}
+
+// parseGOGC accepts a percentage (1-100000) or "off".
+func parseGOGC(s string) (int, error) {
+	if strings.EqualFold(s, "off") {
+		return -1, nil
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 1 || n > 100000 {
+		return 0, fmt.Errorf("invalid GOGC %q (1-100000 or off)", s)
+	}
+	return n, nil
+}
+
+// parseMemoryLimit accepts "off" or a byte count with an optional
+// B/KiB/MiB/GiB suffix, the same units GOMEMLIMIT uses.
+func parseMemoryLimit(s string) (int64, error) {
+	if strings.EqualFold(s, "off") {
+		return math.MaxInt64, nil
+	}
+	num, mult := s, int64(1)
+	for _, u := range []struct {
+		suffix string
+		mult   int64
+	}{{"GiB", 1 << 30}, {"MiB", 1 << 20}, {"KiB", 1 << 10}, {"B", 1}} {
+		if strings.HasSuffix(s, u.suffix) {
+			num, mult = strings.TrimSuffix(s, u.suffix), u.mult
+			break
+		}
+	}
+	n, err := strconv.ParseInt(strings.TrimSpace(num), 10, 64)
+	if err != nil || n <= 0 || n > math.MaxInt64/mult {
+		return 0, fmt.Errorf("invalid GOMEMLIMIT %q (bytes with optional KiB/MiB/GiB suffix, or off)", s)
+	}
+	return n * mult, nil
+}
+
+func formatGOGC(pct int) string {
+	if pct < 0 {
+		return "off"
+	}
+	return strconv.Itoa(pct)
+}
+
+func formatMemoryLimit(n int64) string {
+	switch {
+	case n == math.MaxInt64:
+		return "off"
+	case n%(1<<30) == 0:
+		return fmt.Sprintf("%dGiB", n>>30)
+	case n%(1<<20) == 0:
+		return fmt.Sprintf("%dMiB", n>>20)
+	}
+	return strconv.FormatInt(n, 10)
+}
+
+// errUnboundedHeap rejects GOGC=off without a memory limit, which lets the
+// heap grow without bound.
+var errUnboundedHeap = errors.New("GOGC=off needs a GOMEMLIMIT, or the heap grows without bound")
+
+// checkGCSettings reports settings that must not be applied.
+func checkGCSettings(s gcSettings) error {
+	if s.GOGC < 0 && s.MemoryLimit == math.MaxInt64 {
+		return errUnboundedHeap
+	}
+	return nil
+}
+
+type gcSettingsJSON struct {
+	Current  gcSettings  `json:"current"`
+	Previous *gcSettings `json:"previous,omitempty"`
+	Display  string      `json:"display"`
+}
+
+// handleGCSettings reports (GET) or changes (POST) GOGC and GOMEMLIMIT.
+// POST takes gogc and/or gomemlimit form values; a redirect value (a local
+// path, as sent by the dashboard form) answers with 303 instead of JSON.
+func handleGCSettings(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		cur := activeGCSettings()
+		writeGCSettings(w, gcSettingsJSON{Current: cur, Display: cur.String()})
+	case http.MethodPost:
+		// Holding loadRunning while applying keeps a sweep or experiment
+		// from starting between the check and the change.
+		if !loadRunning.CompareAndSwap(false, true) {
+			http.Error(w, "a load experiment is running", http.StatusConflict)
+			return
+		}
+		defer loadRunning.Store(false)
+		next := activeGCSettings()
+		if s := strings.TrimSpace(r.FormValue("gogc")); s != "" {
+			pct, err := parseGOGC(s)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			next.GOGC = pct
+		}
+		if s := strings.TrimSpace(r.FormValue("gomemlimit")); s != "" {
+			limit, err := parseMemoryLimit(s)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			next.MemoryLimit = limit
+		}
+		if err := checkGCSettings(next); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		prev := applyGCSettings(next)
+		if to := r.FormValue("redirect"); strings.HasPrefix(to, "/") && !strings.HasPrefix(to, "//") {
+			http.Redirect(w, r, to, http.StatusSeeOther)
+			return
+		}
+		writeGCSettings(w, gcSettingsJSON{Current: next, Previous: &prev, Display: next.String()})
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func writeGCSettings(w http.ResponseWriter, v gcSettingsJSON) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"lessonkit/dashboard"
+	"lessonkit/hey"
+)
+
+// maxCompareExperiments is how many recent experiments the comparison
+// page lists next to the imported hey runs.
+const maxCompareExperiments = 5
+
+// heyRun is an archived hey report, named after its file: the lesson's
+// naive_hey_output.txt becomes "naive".
+type heyRun struct {
+	Name   string      `json:"name"`
+	File   string      `json:"file"`
+	Result *hey.Result `json:"result"`
+}
+
+// heyDir is searched for *_hey_output.txt; HEY_DIR overrides the default of
+// the working directory, where start.sh runs the server.
+func heyDir() string {
+	if dir := os.Getenv("HEY_DIR"); dir != "" {
+		return dir
+	}
+	return "."
+}
+
+// loadHeyRuns parses every *_hey_output.txt in dir. Files that fail to
+// parse are logged and skipped so one bad archive does not hide the rest.
+func loadHeyRuns(dir string) []heyRun {
+	paths, _ := filepath.Glob(filepath.Join(dir, "*_hey_output.txt"))
+	var runs []heyRun
+	for _, path := range paths {
+		res, err := hey.ParseFile(path)
+		if err != nil {
+			slog.Warn("skipping hey output", "err", err)
+			continue
+		}
+		name := strings.TrimSuffix(filepath.Base(path), "_hey_output.txt")
+		runs = append(runs, heyRun{Name: name, File: filepath.Base(path), Result: res})
+	}
+	return runs
+}
+
+type compareJSON struct {
+	Imported    []heyRun     `json:"imported"`
+	Experiments []experiment `json:"experiments"`
+}
+
+// handleCompare shows archived hey runs next to recent experiments.
+// ?format=json returns the parsed data instead of the page.
+func handleCompare(w http.ResponseWriter, r *http.Request) {
+	out := compareJSON{Imported: loadHeyRuns(heyDir()), Experiments: experiments.Recent()}
+	if len(out.Experiments) > maxCompareExperiments {
+		out.Experiments = out.Experiments[:maxCompareExperiments]
+	}
+	if r.URL.Query().Get("format") == "json" {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(out)
+		return
+	}
+	comparePage(out).ServeHTTP(w, r)
+}
+
+func comparePage(out compareJSON) *dashboard.Dashboard {
+	d := dashboard.New("Imported vs Measured Runs", dashboard.Light)
+	d.StreamPath = ""
+	d.Intro = "Archived hey reports (*_hey_output.txt) measure full HTTP round trips against /naive and /pooled; experiments call the processors in-process, so compare the ratio between naive and pooled rather than absolute latencies."
+	d.Nav = []dashboard.Link{{Label: "Dashboard", URL: "/"}, {Label: "JSON", URL: "/experiment/compare?format=json"}}
+
+	table := &dashboard.Table{Columns: []string{"Source", "Run", "Requests", "Req/s", "p50", "p90", "p99", "Slowest", "Errors"}}
+	for _, run := range out.Imported {
+		res := run.Result
+		errs := 0
+		for _, n := range res.Errors {
+			errs += n
+		}
+		for code, n := range res.StatusCodes {
+			if code >= 400 {
+				errs += n
+			}
+		}
+		table.Rows = append(table.Rows, []any{"hey: " + run.File, run.Name, res.Requests(), fmt.Sprintf("%.0f", res.RequestsPerSec),
+			heyPercentile(res, 50), heyPercentile(res, 90), heyPercentile(res, 99), res.Slowest, errs})
+	}
+	for _, e := range out.Experiments {
+		for _, run := range e.Runs {
+			table.Rows = append(table.Rows, []any{"experiment " + e.ID + " (" + run.callMode() + ")", run.Processor, run.Ops, fmt.Sprintf("%.0f", run.OpsPerSec),
+				time.Duration(run.P50Ns), time.Duration(run.P90Ns), time.Duration(run.P99Ns), time.Duration(run.MaxNs), run.Errors})
+		}
+	}
+
+	histograms := &dashboard.Table{Columns: []string{"Run", "Response time histogram", "Range"}}
+	for _, run := range out.Imported {
+		var counts []float64
+		for _, b := range run.Result.Histogram {
+			counts = append(counts, float64(b.Count))
+		}
+		span := "—"
+		if h := run.Result.Histogram; len(h) > 0 {
+			span = fmt.Sprintf("%s – %s", h[0].Mark, h[len(h)-1].Mark)
+		}
+		histograms.Rows = append(histograms.Rows, []any{run.Name,
+			dashboard.Chart{Values: counts, Width: 240, Height: 48}, span})
+	}
+
+	d.Add(dashboard.Panel{ID: "runs", Title: "Runs", Render: func() []dashboard.Block {
+		if len(table.Rows) == 0 {
+			return []dashboard.Block{dashboard.Text("No hey output files or experiments found.")}
+		}
+		return []dashboard.Block{table}
+	}})
+	if len(out.Imported) > 0 {
+		d.Add(dashboard.Panel{ID: "histograms", Title: "Imported latency histograms", Render: func() []dashboard.Block {
+			return []dashboard.Block{histograms}
+		}})
+	}
+	return d
+}
+
+func heyPercentile(res *hey.Result, p float64) any {
+	if d, ok := res.Percentile(p); ok {
+		return d
+	}
+	return "—"
+}
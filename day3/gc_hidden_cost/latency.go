@@ -0,0 +1,212 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"math/bits"
+	"sync/atomic"
+	"time"
+)
+
+// latencySubBuckets is the number of linear buckets per power of two, which
+// bounds the percentile error to 1/16 (about 6%) of the reported value.
+const latencySubBuckets = 16
+
+// latencyHistogram is a log-linear histogram of durations in nanoseconds,
+// cheap enough to update on every call of a hot loop. It is not safe for
+// concurrent use; load workers keep one each and merge them at the end.
+type latencyHistogram struct {
+	counts [64 * latencySubBuckets]uint64
+	n      uint64
+	max    time.Duration
+}
+
+func latencyBucket(d time.Duration) int {
+	ns := uint64(d)
+	if d < 0 {
+		ns = 0
+	}
+	if ns < latencySubBuckets {
+		return int(ns)
+	}
+	exp := bits.Len64(ns) - 5
+	return (exp+1)*latencySubBuckets + int(ns>>exp) - latencySubBuckets
+}
+
+// latencyBucketUpper is the exclusive upper bound of bucket i, saturating
+// at math.MaxInt64 for the last bucket.
+func latencyBucketUpper(i int) time.Duration {
+	if i < latencySubBuckets {
+		return time.Duration(i + 1)
+	}
+	exp := i/latencySubBuckets - 1
+	if upper := time.Duration(latencySubBuckets+i%latencySubBuckets+1) << exp; upper > 0 {
+		return upper
+	}
+	return math.MaxInt64
+}
+
+func (h *latencyHistogram) Record(d time.Duration) {
+	h.counts[latencyBucket(d)]++
+	h.n++
+	if d > h.max {
+		h.max = d
+	}
+}
+
+func (h *latencyHistogram) Merge(o *latencyHistogram) {
+	for i, c := range o.counts {
+		h.counts[i] += c
+	}
+	h.n += o.n
+	if o.max > h.max {
+		h.max = o.max
+	}
+}
+
+func (h *latencyHistogram) Count() uint64      { return h.n }
+func (h *latencyHistogram) Max() time.Duration { return h.max }
+
+// Quantile returns the upper bound of the bucket holding the q-th sample,
+// capped at the largest value recorded. It is 0 for an empty histogram.
+func (h *latencyHistogram) Quantile(q float64) time.Duration {
+	if h.n == 0 {
+		return 0
+	}
+	rank := uint64(q * float64(h.n))
+	if rank >= h.n {
+		rank = h.n - 1
+	}
+	var seen uint64
+	for i, c := range h.counts {
+		seen += c
+		if seen > rank {
+			return min(latencyBucketUpper(i), h.max)
+		}
+	}
+	return h.max
+}
+
+// Rolling windows for latencyTracker: latencySlots slots of latencySlotWidth
+// each, so the recent percentiles cover the last minute.
+const (
+	latencySlots     = 6
+	latencySlotWidth = 10 * time.Second
+	latencyWindow    = latencySlots * latencySlotWidth
+)
+
+// atomicHistogram is latencyHistogram's bucket layout with atomic counters,
+// so request handlers can record into it concurrently without a lock.
+type atomicHistogram struct {
+	counts [64 * latencySubBuckets]atomic.Uint64
+	n      atomic.Uint64
+	max    atomic.Int64
+}
+
+func (h *atomicHistogram) record(d time.Duration) {
+	h.counts[latencyBucket(d)].Add(1)
+	h.n.Add(1)
+	for cur := h.max.Load(); int64(d) > cur && !h.max.CompareAndSwap(cur, int64(d)); cur = h.max.Load() {
+	}
+}
+
+func (h *atomicHistogram) reset() {
+	for i := range h.counts {
+		h.counts[i].Store(0)
+	}
+	h.n.Store(0)
+	h.max.Store(0)
+}
+
+// addTo adds the counters to dst. Concurrent records may be half visible,
+// which skews a snapshot by at most the samples in flight.
+func (h *atomicHistogram) addTo(dst *latencyHistogram) {
+	for i := range h.counts {
+		dst.counts[i] += h.counts[i].Load()
+	}
+	dst.n += h.n.Load()
+	if m := time.Duration(h.max.Load()); m > dst.max {
+		dst.max = m
+	}
+}
+
+// latencySlot is one rolling-window slot, tagged with the slot number
+// (time since the epoch divided by latencySlotWidth) it currently holds.
+type latencySlot struct {
+	epoch atomic.Int64
+	hist  atomicHistogram
+}
+
+// latencyTracker keeps a processor's latencies since start and over the
+// last latencyWindow. The first record of a new slot period clears the
+// slot it reuses; samples racing with that reset may be lost, an accepted
+// cost of staying lock-free on the request path.
+type latencyTracker struct {
+	total atomicHistogram
+	slots [latencySlots]latencySlot
+	now   func() time.Time // for tests
+}
+
+func newLatencyTracker() *latencyTracker {
+	return &latencyTracker{now: time.Now}
+}
+
+func (t *latencyTracker) Record(d time.Duration) {
+	t.total.record(d)
+	epoch := t.now().UnixNano() / int64(latencySlotWidth)
+	s := &t.slots[epoch%latencySlots]
+	if old := s.epoch.Load(); old != epoch && s.epoch.CompareAndSwap(old, epoch) {
+		s.hist.reset()
+	}
+	s.hist.record(d)
+}
+
+// Count is the number of latencies recorded since start.
+func (t *latencyTracker) Count() uint64 { return t.total.n.Load() }
+
+// latencyStats summarises a histogram for /api/stats and the dashboard.
+type latencyStats struct {
+	Count uint64 `json:"count"`
+	P50Ns int64  `json:"p50_ns"`
+	P95Ns int64  `json:"p95_ns"`
+	P99Ns int64  `json:"p99_ns"`
+	MaxNs int64  `json:"max_ns"`
+}
+
+func summarize(h *latencyHistogram) latencyStats {
+	return latencyStats{
+		Count: h.Count(),
+		P50Ns: int64(h.Quantile(0.50)),
+		P95Ns: int64(h.Quantile(0.95)),
+		P99Ns: int64(h.Quantile(0.99)),
+		MaxNs: int64(h.Max()),
+	}
+}
+
+// latencySummary is a tracker's all-time and last-minute statistics.
+type latencySummary struct {
+	Total  latencyStats `json:"total"`
+	Recent latencyStats `json:"last_1m"`
+}
+
+func (t *latencyTracker) Summary() latencySummary {
+	var total, recent latencyHistogram
+	t.total.addTo(&total)
+	epoch := t.now().UnixNano() / int64(latencySlotWidth)
+	for i := range t.slots {
+		s := &t.slots[i]
+		if e := s.epoch.Load(); e > epoch-latencySlots && e <= epoch {
+			s.hist.addTo(&recent)
+		}
+	}
+	return latencySummary{Total: summarize(&total), Recent: summarize(&recent)}
+}
+
+// latencyCell formats stats as "p50 / p95 / p99 (max)" for dashboard tables.
+func latencyCell(st latencyStats) string {
+	if st.Count == 0 {
+		return "—"
+	}
+	return fmt.Sprintf("%s / %s / %s (max %s)", time.Duration(st.P50Ns), time.Duration(st.P95Ns),
+		time.Duration(st.P99Ns), time.Duration(st.MaxNs))
+}
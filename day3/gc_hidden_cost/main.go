@@ -3,33 +3,27 @@ package main
 import (
 	"encoding/json"
 	"fmt"
-	"log"
+	"log/slog"
 	"net/http"
 	"os"
 	"runtime"
 	"strconv"
-	"sync"
 	"time"
 
 	"gc_hidden_cost/processor"
+	"lessonkit/logging"
+	"lessonkit/metrics"
+	"lessonkit/tracing"
 )
 
-// Global processors for demonstration
-var naiveProcessor *processor.NaiveProcessor
-var pooledProcessor *processor.PooledProcessor
-
-// Live stats for dashboard (request counts and last duration per processor type)
+// Prometheus metrics served at /metrics
 var (
-	statsMu                    sync.RWMutex
-	naiveRequestCount           uint64
-	pooledRequestCount          uint64
-	lastNaiveDurationNs         int64
-	lastPooledDurationNs        int64
+	promRegistry   = metrics.NewRegistry()
+	requestMetrics = metrics.NewRequestMetrics(promRegistry)
 )
 
 func init() {
-	naiveProcessor = processor.NewNaiveProcessor()
-	pooledProcessor = processor.NewPooledProcessor()
+	metrics.RegisterRuntimeMetrics(promRegistry)
 }
 
 // NewMux returns the application's HTTP mux for testing and reuse.
@@ -37,10 +31,19 @@ func NewMux() *http.ServeMux {
 	mux := http.NewServeMux()
 	mux.HandleFunc("/", dashboardHandler)
 	mux.HandleFunc("/dashboard", dashboardHandler)
-	mux.HandleFunc("/naive", handleProcessWithStats(naiveProcessor, recordNaiveStats))
-	mux.HandleFunc("/pooled", handleProcessWithStats(pooledProcessor, recordPooledStats))
+	mux.Handle(dash.StreamPath, dash.Stream())
+	mux.Handle(dash.EventsPath, dash.Events(handlerStats))
+	registry.Routes(mux)
 	mux.HandleFunc("/debug/mem", handleMemStats)
+	mux.HandleFunc("/debug/gc", handleGCDebug)
+	mux.HandleFunc("/admin/gc", handleGCSettings)
+	mux.HandleFunc("/experiment/gogc", handleGOGCSweep)
+	mux.HandleFunc("/experiment/run", handleExperimentRun)
+	mux.HandleFunc("GET /experiment/results", handleExperimentResults)
+	mux.HandleFunc("GET /experiment/results/{id}", handleExperimentResult)
+	mux.HandleFunc("GET /experiment/compare", handleCompare)
 	mux.HandleFunc("/api/stats", handleStatsJSON)
+	mux.Handle("/metrics", promRegistry.Handler())
 	return mux
 }
 
@@ -50,16 +53,28 @@ func main() {
 		port = "8080"
 	}
 	addr := fmt.Sprintf(":%s", port)
-	log.Printf("Server starting on %s...", addr)
-	log.Fatal(http.ListenAndServe(addr, NewMux()))
-}
 
-func handleProcess(p processor.Processor) http.HandlerFunc {
-	return handleProcessWithStats(p, nil)
+	logger, closeLog := logging.New(os.Stderr, logging.OptionsFromEnv())
+	slog.SetDefault(logger)
+	tracer, err := tracing.FromEnv("gc_hidden_cost")
+	if err != nil {
+		logger.Error("tracing disabled", "err", err)
+		tracer = tracing.NewTracer("gc_hidden_cost", nil)
+	}
+	logger.Info("server starting", "addr", addr)
+	err = http.ListenAndServe(addr, tracing.Middleware(tracer, logging.Middleware(logger, NewMux())))
+	logger.Error("server stopped", "err", err)
+	tracer.Close()
+	closeLog()
+	os.Exit(1)
 }
 
 func handleProcessWithStats(p processor.Processor, recordStats func(time.Duration)) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			handleBody(w, r, p, recordStats)
+			return
+		}
 		sizeStr := r.URL.Query().Get("size")
 		if sizeStr == "" {
 			http.Error(w, "missing 'size' parameter", http.StatusBadRequest)
@@ -71,10 +86,29 @@ func handleProcessWithStats(p processor.Processor, recordStats func(time.Duratio
 			http.Error(w, "invalid 'size' parameter", http.StatusBadRequest)
 			return
 		}
+		release, ok := admit.admitSize(w, r, int64(size), processor.Footprint(p, size))
+		if !ok {
+			return
+		}
+		defer release()
+
+		if s := r.URL.Query().Get("stream"); s == "1" || s == "true" {
+			streamProcess(w, r, p, size, recordStats)
+			return
+		}
+		if r.Context().Err() != nil {
+			return // the client is gone; skip the work
+		}
 
+		_, span := tracing.Start(r.Context(), "process")
+		span.SetAttr("size", sizeStr)
 		start := time.Now()
 		result, err := p.Process(size)
 		duration := time.Since(start)
+		if err != nil {
+			span.SetError(err.Error())
+		}
+		span.End()
 		if err != nil {
 			http.Error(w, fmt.Sprintf("processing error: %v", err), http.StatusInternalServerError)
 			return
@@ -83,42 +117,19 @@ func handleProcessWithStats(p processor.Processor, recordStats func(time.Duratio
 			recordStats(duration)
 		}
 
+		_, span = tracing.Start(r.Context(), "encode")
+		defer span.End()
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(map[string]string{
 			"status":   "success",
 			"message":  result,
 			"duration": duration.String(),
 			"processor": fmt.Sprintf("%T", p),
+			"gc_settings": activeGCSettings().String(),
 		})
 	}
 }
 
-func recordNaiveStats(d time.Duration) {
-	statsMu.Lock()
-	naiveRequestCount++
-	lastNaiveDurationNs = d.Nanoseconds()
-	statsMu.Unlock()
-}
-
-func recordPooledStats(d time.Duration) {
-	statsMu.Lock()
-	pooledRequestCount++
-	lastPooledDurationNs = d.Nanoseconds()
-	statsMu.Unlock()
-}
-
-func handleStatsJSON(w http.ResponseWriter, r *http.Request) {
-	statsMu.RLock()
-	defer statsMu.RUnlock()
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]interface{}{
-		"naive_request_count":    naiveRequestCount,
-		"pooled_request_count":   pooledRequestCount,
-		"last_naive_duration_ns": lastNaiveDurationNs,
-		"last_pooled_duration_ns": lastPooledDurationNs,
-	})
-}
-
 func handleMemStats(w http.ResponseWriter, r *http.Request) {
 	var m runtime.MemStats
 	runtime.ReadMemStats(&m)
@@ -136,79 +147,3 @@ func handleMemStats(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
-func dashboardHandler(w http.ResponseWriter, r *http.Request) {
-	var m runtime.MemStats
-	runtime.ReadMemStats(&m)
-	lastGCStr := time.Unix(0, int64(m.LastGC)).Format(time.RFC3339Nano)
-
-	statsMu.RLock()
-	naiveCnt := naiveRequestCount
-	pooledCnt := pooledRequestCount
-	lastNaiveNs := lastNaiveDurationNs
-	lastPooledNs := lastPooledDurationNs
-	statsMu.RUnlock()
-
-	lastNaiveStr := "—"
-	if lastNaiveNs > 0 {
-		lastNaiveStr = time.Duration(lastNaiveNs).String()
-	}
-	lastPooledStr := "—"
-	if lastPooledNs > 0 {
-		lastPooledStr = time.Duration(lastPooledNs).String()
-	}
-
-	w.Header().Set("Content-Type", "text/html; charset=utf-8")
-	html := fmt.Sprintf(`<!DOCTYPE html>
-<html>
-<head><title>GC Hidden Cost Dashboard</title>
-<meta http-equiv="refresh" content="2">
-<style>
-body{font-family:system-ui,sans-serif;margin:24px;background:#f8f9fa;color:#212529;}
-h1{color:#212529;font-weight:600;}
-h2{color:#495057;font-size:1.1rem;margin-top:24px;}
-.info{background:#fff;border:1px solid #dee2e6;border-radius:8px;padding:16px;margin:12px 0;color:#495057;line-height:1.5;}
-.metric{background:#fff;border:1px solid #dee2e6;padding:12px 16px;margin:8px 0;border-radius:8px;}
-.metric strong{color:#212529;}
-.nav{margin:16px 0;}
-.nav a{color:#495057;margin-right:16px;text-decoration:none;}
-.nav a:hover{text-decoration:underline;}
-.live{background:#fff3cd;border:1px solid #ffc107;color:#856404;}
-.actions{margin:16px 0;}
-.actions a{display:inline-block;margin-right:8px;padding:8px 16px;background:#212529;color:#fff;border-radius:6px;text-decoration:none;}
-.actions a:hover{background:#495057;}
-</style>
-</head>
-<body>
-<h1>GC Hidden Cost Dashboard</h1>
-<div class="info">
-<strong>About this project:</strong> This app compares two ways of handling per-request buffers in Go: <b>Naive</b> (new allocation every time) vs <b>Pooled</b> (sync.Pool reuse). Use the actions below to generate load; watch GC metrics and live request stats to see how pooling reduces allocations and GC pressure.
-</div>
-<p class="nav"><a href="/">Dashboard</a> | <a href="/debug/mem">JSON MemStats</a> | <a href="/api/stats">JSON Live Stats</a></p>
-
-<h2>Live request metrics</h2>
-<p>Click the buttons to run requests; counts and last duration update in real time (page auto-refreshes every 2s).</p>
-<div class="metric live"><strong>Naive requests:</strong> %d &nbsp;|&nbsp; <strong>Last duration:</strong> %s</div>
-<div class="metric live"><strong>Pooled requests:</strong> %d &nbsp;|&nbsp; <strong>Last duration:</strong> %s</div>
-<div class="actions">
-<a href="/naive?size=4096">Run Naive (4KB)</a>
-<a href="/naive?size=65536">Run Naive (64KB)</a>
-<a href="/pooled?size=4096">Run Pooled (4KB)</a>
-<a href="/pooled?size=65536">Run Pooled (64KB)</a>
-</div>
-
-<h2>GC &amp; memory metrics</h2>
-<div class="metric"><strong>Alloc (bytes):</strong> %d</div>
-<div class="metric"><strong>TotalAlloc (bytes):</strong> %d</div>
-<div class="metric"><strong>Sys (bytes):</strong> %d</div>
-<div class="metric"><strong>NumGC:</strong> %d</div>
-<div class="metric"><strong>GCCPUFraction:</strong> %f</div>
-<div class="metric"><strong>PauseTotalNs:</strong> %d</div>
-<div class="metric"><strong>HeapObjects:</strong> %d</div>
-<div class="metric"><strong>LastGC:</strong> %s</div>
-<p><em>Auto-refresh 2s.</em></p>
-</body>
-</html>`,
-		naiveCnt, lastNaiveStr, pooledCnt, lastPooledStr,
-		m.Alloc, m.TotalAlloc, m.Sys, m.NumGC, m.GCCPUFraction, m.PauseTotalNs, m.HeapObjects, lastGCStr)
-	fmt.Fprint(w, html)
-}
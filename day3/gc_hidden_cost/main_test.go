@@ -1,10 +1,23 @@
 package main
 
 import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
 	"strings"
+	"sync"
 	"testing"
+	"time"
+
+	"gc_hidden_cost/processor"
+	"lessonkit/dashboard"
 )
 
 func TestDashboardHandler(t *testing.T) {
@@ -73,3 +86,638 @@ func TestPooledHandler(t *testing.T) {
 		t.Errorf("pooled body = %s", w.Body.String())
 	}
 }
+
+func TestPrometheusMetrics(t *testing.T) {
+	mux := NewMux()
+	mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/pooled?size=1024", nil))
+	req := httptest.NewRequest("GET", "/metrics", nil)
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Errorf("metrics status = %d, want 200", w.Code)
+	}
+	for _, want := range []string{`http_requests_total{handler="pooled"}`, "go_gc_pauses_seconds_bucket"} {
+		if !strings.Contains(w.Body.String(), want) {
+			t.Errorf("metrics body missing %q", want)
+		}
+	}
+}
+
+func TestEventsStreamReportsHandlerStats(t *testing.T) {
+	srv := httptest.NewServer(NewMux())
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/events?interval=50")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("Content-Type = %q", ct)
+	}
+	for i := 0; i < 5; i++ {
+		r, err := http.Get(srv.URL + "/pooled?size=4096")
+		if err != nil {
+			t.Fatal(err)
+		}
+		r.Body.Close()
+	}
+
+	sc := bufio.NewScanner(resp.Body)
+	for sc.Scan() {
+		line := sc.Text()
+		if !strings.HasPrefix(line, "data: ") {
+			continue
+		}
+		var ev dashboard.EventSample
+		if err := json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &ev); err != nil {
+			t.Fatalf("bad event %q: %v", line, err)
+		}
+		if h, ok := ev.Handlers["pooled"]; !ok || h.Requests < 5 || h.P99Ns <= 0 {
+			t.Errorf("pooled handler stats = %+v", ev.Handlers)
+		}
+		return
+	}
+	t.Fatal("stream ended without a stats event")
+}
+
+func TestGCDebugHandler(t *testing.T) {
+	runtime.GC()
+	runtime.GC()
+	w := httptest.NewRecorder()
+	handleGCDebug(w, httptest.NewRequest("GET", "/debug/gc?cycles=2", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", w.Code)
+	}
+	var rep gcDebugReport
+	if err := json.Unmarshal(w.Body.Bytes(), &rep); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if rep.NumGC < 2 || len(rep.Cycles) != 2 || rep.Cycles[1].Num != rep.NumGC {
+		t.Errorf("num_gc = %d, cycles = %+v", rep.NumGC, rep.Cycles)
+	}
+	if rep.HeapGoalBytes == 0 || rep.CPU.TotalSeconds <= 0 {
+		t.Errorf("heap goal = %d, cpu = %+v", rep.HeapGoalBytes, rep.CPU)
+	}
+	pauses, ok := rep.Histograms["/gc/pauses:seconds"]
+	if !ok || pauses.Count == 0 || len(pauses.Buckets) == 0 || pauses.P99 < pauses.P50 {
+		t.Errorf("pause histogram = %+v", pauses)
+	}
+	if _, ok := rep.Histograms["/sched/latencies:seconds"]; !ok {
+		t.Error("missing scheduler latency histogram")
+	}
+
+	w = httptest.NewRecorder()
+	handleGCDebug(w, httptest.NewRequest("GET", "/debug/gc?cycles=-1", nil))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("bad cycles status = %d, want 400", w.Code)
+	}
+}
+
+func TestGCSettingsAdmin(t *testing.T) {
+	orig := activeGCSettings()
+	defer applyGCSettings(orig)
+
+	form := strings.NewReader("gogc=250&gomemlimit=512MiB")
+	req := httptest.NewRequest("POST", "/admin/gc", form)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w := httptest.NewRecorder()
+	handleGCSettings(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("POST status = %d: %s", w.Code, w.Body.String())
+	}
+	var got gcSettingsJSON
+	if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Current.GOGC != 250 || got.Current.MemoryLimit != 512<<20 || got.Previous == nil || *got.Previous != orig {
+		t.Errorf("POST response = %+v", got)
+	}
+	if debug.SetGCPercent(250) != 250 {
+		t.Error("runtime GOGC was not changed")
+	}
+
+	w = httptest.NewRecorder()
+	handleNaiveForTest(w)
+	if !strings.Contains(w.Body.String(), "GOGC=250 GOMEMLIMIT=512MiB") {
+		t.Errorf("request response missing GC settings: %s", w.Body.String())
+	}
+
+	req = httptest.NewRequest("POST", "/admin/gc?gogc=off&redirect=/", nil)
+	w = httptest.NewRecorder()
+	handleGCSettings(w, req)
+	if w.Code != http.StatusSeeOther || activeGCSettings().GOGC != -1 {
+		t.Errorf("redirect POST = %d, settings = %+v", w.Code, activeGCSettings())
+	}
+
+	// GOGC is off now, so dropping the memory limit would unbound the heap.
+	for _, bad := range []string{"gogc=0", "gogc=abc", "gomemlimit=12XB", "gomemlimit=-5", "gomemlimit=off"} {
+		w = httptest.NewRecorder()
+		handleGCSettings(w, httptest.NewRequest("POST", "/admin/gc?"+bad, nil))
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("POST %s status = %d, want 400", bad, w.Code)
+		}
+	}
+	if activeGCSettings().MemoryLimit != 512<<20 {
+		t.Errorf("rejected POST changed settings: %+v", activeGCSettings())
+	}
+
+	loadRunning.Store(true)
+	w = httptest.NewRecorder()
+	handleGCSettings(w, httptest.NewRequest("POST", "/admin/gc?gogc=100", nil))
+	loadRunning.Store(false)
+	if w.Code != http.StatusConflict {
+		t.Errorf("POST during a load run = %d, want 409", w.Code)
+	}
+}
+
+func handleNaiveForTest(w *httptest.ResponseRecorder) {
+	naive, _ := registry.Lookup("naive")
+	naive.ServeHTTP(w, httptest.NewRequest("GET", "/process/naive?size=64", nil))
+}
+
+func TestGOGCSweep(t *testing.T) {
+	orig := activeGCSettings()
+	w := httptest.NewRecorder()
+	handleGOGCSweep(w, httptest.NewRequest("POST", "/experiment/gogc?values=50,200&size=1024&concurrency=2&duration=100ms&format=json", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", w.Code, w.Body.String())
+	}
+	var out sweepJSON
+	if err := json.Unmarshal(w.Body.Bytes(), &out); err != nil {
+		t.Fatal(err)
+	}
+	if len(out.Results) != 4 {
+		t.Fatalf("got %d results, want 4", len(out.Results))
+	}
+	for i, want := range []string{"50", "50", "200", "200"} {
+		if r := out.Results[i]; r.GOGC != want || r.Ops == 0 || r.PeakHeapBytes == 0 {
+			t.Errorf("result %d = %+v, want GOGC %s with ops and heap samples", i, r, want)
+		}
+	}
+	if activeGCSettings() != orig {
+		t.Errorf("settings not restored: %+v, want %+v", activeGCSettings(), orig)
+	}
+
+	w = httptest.NewRecorder()
+	handleGOGCSweep(w, httptest.NewRequest("POST", "/experiment/gogc?values=100&duration=10s", nil))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("over-long sweep status = %d, want 400", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	handleGOGCSweep(w, httptest.NewRequest("GET", "/experiment/gogc?values=100&duration=100ms", nil))
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("GET sweep status = %d, want 405", w.Code)
+	}
+}
+
+func TestLatencyHistogramQuantiles(t *testing.T) {
+	var h latencyHistogram
+	for i := 1; i <= 1000; i++ {
+		h.Record(time.Duration(i) * time.Microsecond)
+	}
+	for _, c := range []struct {
+		q    float64
+		want time.Duration
+	}{{0.5, 500 * time.Microsecond}, {0.99, 990 * time.Microsecond}, {1, time.Millisecond}} {
+		got := h.Quantile(c.q)
+		if got < c.want || float64(got) > float64(c.want)*1.07 {
+			t.Errorf("Quantile(%v) = %v, want within 7%% above %v", c.q, got, c.want)
+		}
+	}
+	var empty latencyHistogram
+	if empty.Quantile(0.5) != 0 {
+		t.Error("empty histogram quantile is not zero")
+	}
+	for _, d := range []time.Duration{0, 15, 16, 31, 32, 1000, time.Second, 1 << 62} {
+		if i := latencyBucket(d); d >= latencyBucketUpper(i) || (i > 0 && d < latencyBucketUpper(i-1)) {
+			t.Errorf("%d ns landed in bucket %d with upper bound %d", d, i, latencyBucketUpper(i))
+		}
+	}
+}
+
+func TestLatencyTrackerWindows(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	tr := newLatencyTracker()
+	tr.now = func() time.Time { return now }
+
+	tr.Record(2 * time.Second) // an outlier that should age out of the window
+	now = now.Add(latencyWindow)
+	var wg sync.WaitGroup
+	for g := 0; g < 4; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 250; i++ {
+				tr.Record(time.Millisecond)
+			}
+		}()
+	}
+	wg.Wait()
+
+	sum := tr.Summary()
+	if sum.Total.Count != 1001 || sum.Total.MaxNs != int64(2*time.Second) {
+		t.Errorf("total = %+v, want 1001 samples with the 2s max", sum.Total)
+	}
+	if sum.Recent.Count != 1000 || sum.Recent.MaxNs != int64(time.Millisecond) || sum.Recent.P99Ns != int64(time.Millisecond) {
+		t.Errorf("last minute = %+v, want 1000 samples of 1ms", sum.Recent)
+	}
+	now = now.Add(latencyWindow)
+	if sum := tr.Summary(); sum.Recent.Count != 0 || sum.Total.Count != 1001 {
+		t.Errorf("after an idle window: %+v", sum)
+	}
+
+	mux := NewMux()
+	mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/naive?size=64", nil))
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest("GET", "/api/stats", nil))
+	var stats struct {
+		Processors map[string]processorStatsJSON `json:"processors"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &stats); err != nil {
+		t.Fatal(err)
+	}
+	if st := stats.Processors["naive"].Latency.Recent; st.Count == 0 || st.P50Ns <= 0 || st.P99Ns < st.P50Ns || st.MaxNs < st.P99Ns {
+		t.Errorf("/api/stats naive latency = %+v", stats.Processors["naive"].Latency)
+	}
+}
+
+func TestExperimentRunStoresResults(t *testing.T) {
+	orig := experiments
+	experiments = newExperimentStore(t.TempDir())
+	defer func() { experiments = orig }()
+	mux := NewMux()
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest("GET", "/experiment/run", nil))
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("GET /experiment/run status = %d, want 405", w.Code)
+	}
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest("POST", "/experiment/run?processors=naive,bogus", nil))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("unknown processor status = %d, want 400", w.Code)
+	}
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest("POST", "/experiment/run?processors="+strings.Repeat("naive,", maxExperimentProcessors)+"naive", nil))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("too many processors status = %d, want 400", w.Code)
+	}
+
+	// A failed save must not leave later experiments locked out.
+	blocked := filepath.Join(t.TempDir(), "file")
+	if err := os.WriteFile(blocked, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	experiments = newExperimentStore(blocked)
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest("POST", "/experiment/run?size=64&concurrency=1&duration=100ms", nil))
+	if w.Code != http.StatusInternalServerError || loadRunning.Load() {
+		t.Errorf("failed save status = %d, loadRunning = %v", w.Code, loadRunning.Load())
+	}
+	experiments = newExperimentStore(t.TempDir())
+
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest("POST", "/experiment/run?size=4096&concurrency=2&duration=100ms", nil))
+	if w.Code != http.StatusCreated {
+		t.Fatalf("status = %d: %s", w.Code, w.Body.String())
+	}
+	var e experiment
+	if err := json.Unmarshal(w.Body.Bytes(), &e); err != nil {
+		t.Fatal(err)
+	}
+	if len(e.Runs) != 2 || e.Runs[0].Processor != "naive" || e.Runs[1].Processor != "pooled" {
+		t.Fatalf("runs = %+v", e.Runs)
+	}
+	for _, run := range e.Runs {
+		if run.Ops == 0 || run.P50Ns <= 0 || run.P99Ns < run.P50Ns || run.MaxNs < run.P99Ns || run.AllocBytesPerS <= 0 || run.Call != "ProcessTo" {
+			t.Errorf("%s run missing measurements: %+v", run.Processor, run)
+		}
+	}
+	if (loadResult{}).callMode() != "Process" {
+		t.Error("a result without Call should read as Process")
+	}
+	if loc := w.Header().Get("Location"); loc != "/experiment/results/"+e.ID {
+		t.Errorf("Location = %q", loc)
+	}
+
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest("GET", "/experiment/results/"+e.ID, nil))
+	var stored experiment
+	if err := json.Unmarshal(w.Body.Bytes(), &stored); err != nil || stored.ID != e.ID || len(stored.Runs) != 2 {
+		t.Errorf("stored experiment = %+v, err %v", stored, err)
+	}
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest("GET", "/experiment/results/..%2fmain", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("bad ID status = %d, want 404", w.Code)
+	}
+
+	// A fresh store reloads results from disk.
+	experiments = newExperimentStore(experiments.dir)
+	if recent := experiments.Recent(); len(recent) != 1 || recent[0].ID != e.ID {
+		t.Errorf("reloaded recent = %+v", recent)
+	}
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	body := w.Body.String()
+	if !strings.Contains(body, "Experiment "+e.ID) || !strings.Contains(body, "<th>pooled</th>") || !strings.Contains(body, "Mallocs/op") {
+		t.Error("dashboard missing side-by-side experiment table")
+	}
+}
+
+func TestCompareImportsArchivedHeyRuns(t *testing.T) {
+	runs := loadHeyRuns(".")
+	if len(runs) != 2 || runs[0].Name != "naive" || runs[1].Name != "pooled" {
+		t.Fatalf("runs = %+v", runs)
+	}
+	for _, run := range runs {
+		if run.Result.Requests() != 500000 || run.Result.RequestsPerSec == 0 {
+			t.Errorf("%s: requests = %d, rps = %v", run.Name, run.Result.Requests(), run.Result.RequestsPerSec)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	NewMux().ServeHTTP(w, httptest.NewRequest("GET", "/experiment/compare", nil))
+	body := w.Body.String()
+	for _, want := range []string{"hey: naive_hey_output.txt", "hey: pooled_hey_output.txt", "84879", "Imported latency histograms"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("compare page missing %q", want)
+		}
+	}
+}
+
+func TestStatsJSONReportsBufferPool(t *testing.T) {
+	mux := NewMux()
+	for _, size := range []string{"4096", "65536", "4096"} {
+		mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/pooled?size="+size, nil))
+	}
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest("GET", "/api/stats", nil))
+	var stats struct {
+		Processors map[string]struct {
+			Reuse processor.PoolStats `json:"reuse"`
+		} `json:"processors"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &stats); err != nil {
+		t.Fatal(err)
+	}
+	pool := stats.Processors["pooled"].Reuse
+	used := map[int]uint64{}
+	for _, c := range pool.Classes {
+		used[c.Size] = c.Hits + c.Misses
+	}
+	if used[4096] < 2 || used[65536] < 1 {
+		t.Errorf("buffer pool classes = %+v", pool.Classes)
+	}
+	if pool.Puts < 3 || pool.ReuseRate() == 0 {
+		t.Errorf("buffer pool totals = %+v", pool)
+	}
+
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	if body := w.Body.String(); !strings.Contains(body, "Reuse rate") || !strings.Contains(body, "<td>64 KB</td>") {
+		t.Error("dashboard missing buffer pool panel")
+	}
+}
+
+func TestProcessorRegistryRoutes(t *testing.T) {
+	mux := NewMux()
+	for _, p := range registry.All() {
+		for _, path := range []string{"/process/" + p.name, "/" + p.name} {
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, httptest.NewRequest("GET", path+"?size=4096", nil))
+			if w.Code != http.StatusOK {
+				t.Errorf("%s status = %d: %s", path, w.Code, w.Body.String())
+			}
+		}
+	}
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest("GET", "/process/nope?size=64", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("unknown processor status = %d, want 404", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest("GET", "/api/stats", nil))
+	var stats struct {
+		Processors map[string]processorStatsJSON `json:"processors"`
+		// The keys /api/stats had before the registry.
+		NaiveCount  uint64               `json:"naive_request_count"`
+		PooledCount uint64               `json:"pooled_request_count"`
+		NaiveGC     *gcSettings          `json:"last_naive_gc_settings"`
+		PooledGC    *gcSettings          `json:"last_pooled_gc_settings"`
+		Pool        *processor.PoolStats `json:"pooled_buffer_pool"`
+		Strategies  map[string]struct {
+			Requests uint64 `json:"request_count"`
+		} `json:"strategies"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &stats); err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range registry.All() {
+		if st := stats.Processors[p.name]; st.RequestCount < 2 || st.Label != p.label {
+			t.Errorf("/api/stats for %s = %+v", p.name, st)
+		}
+	}
+	if stats.NaiveCount < 2 || stats.PooledCount < 2 || stats.NaiveGC == nil || stats.PooledGC == nil || stats.Pool == nil || stats.Pool.Gets < 2 {
+		t.Errorf("/api/stats compatibility keys = %+v", stats)
+	}
+	if _, ok := stats.Strategies["naive"]; ok || stats.Strategies["stack"].Requests < 2 {
+		t.Errorf("/api/stats strategies = %+v", stats.Strategies)
+	}
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	for _, p := range registry.All() {
+		if body := w.Body.String(); !strings.Contains(body, "<td>"+p.label+"</td>") || !strings.Contains(body, "/process/"+p.name+"?size=4096") {
+			t.Errorf("dashboard does not list %s", p.name)
+		}
+	}
+	for _, want := range []string{"Last naive request ran with", "Last pooled request ran with"} {
+		if !strings.Contains(w.Body.String(), want) {
+			t.Errorf("dashboard missing %q", want)
+		}
+	}
+
+	reg := newProcessorRegistry()
+	reg.Register("naive", "Naive", func() processor.Processor { return processor.NewNaiveProcessor() })
+	defer func() {
+		if recover() == nil {
+			t.Error("registering a name twice did not panic")
+		}
+	}()
+	reg.Register("naive", "Again", func() processor.Processor { return processor.NewNaiveProcessor() })
+}
+
+func TestStreamMode(t *testing.T) {
+	mux := NewMux()
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest("GET", "/pooled?size=100000&stream=1", nil))
+	if w.Code != http.StatusOK || w.Body.Len() != 100000 || w.Header().Get("Content-Length") != "100000" {
+		t.Fatalf("status %d, %d bytes, Content-Length %q", w.Code, w.Body.Len(), w.Header().Get("Content-Length"))
+	}
+	if b := w.Body.Bytes(); b[0] != 0 || b[255] != 255 || b[99999] != byte(99999%256) {
+		t.Error("streamed body does not match the processor pattern")
+	}
+
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest("GET", "/naive?size=10&stream=1&timeout=forever", nil))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("bad timeout status = %d, want 400", w.Code)
+	}
+
+	naive, _ := registry.Lookup("naive")
+	before := naive.latency.Count()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest("GET", "/naive?size=1000000&stream=1", nil).WithContext(ctx))
+	after := naive.latency.Count()
+	if w.Body.Len() != 0 || after != before {
+		t.Errorf("disconnected client got %d bytes and %d recorded requests", w.Body.Len(), after-before)
+	}
+}
+
+func TestPostBodyIngestion(t *testing.T) {
+	mux := NewMux()
+	for _, path := range []string{"/naive", "/pooled"} {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest("POST", path, strings.NewReader("hello, body")))
+		if w.Code != http.StatusOK || w.Body.String() != "HELLO, BODY" {
+			t.Errorf("POST %s = %d %q", path, w.Code, w.Body.String())
+		}
+	}
+
+	req := httptest.NewRequest("POST", "/pooled", strings.NewReader("x"))
+	req.ContentLength = admit.cfg.MaxBodyBytes + 1
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("declared oversize body status = %d, want 413", w.Code)
+	}
+
+	// Without a Content-Length the limit is enforced while reading.
+	req = httptest.NewRequest("POST", "/naive", io.LimitReader(zeroReader{}, admit.cfg.MaxBodyBytes+1))
+	req.ContentLength = -1
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("streamed oversize body status = %d, want 413", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest("POST", "/stack", strings.NewReader("x")))
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("POST to a processor without body support = %d, want 405", w.Code)
+	}
+}
+
+type zeroReader struct{}
+
+func (zeroReader) Read(p []byte) (int, error) {
+	clear(p)
+	return len(p), nil
+}
+
+func TestAdmissionControl(t *testing.T) {
+	orig := admit
+	defer func() { admit = orig }()
+	admit = newAdmission(admissionConfig{MaxSize: 1 << 20, MaxBodyBytes: 1 << 10, MaxInFlight: 2, MaxInFlightBytes: 1 << 20})
+	mux := NewMux()
+	get := func(target string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest("GET", target, nil))
+		return w
+	}
+
+	if w := get("/process/naive?size=2000000000"); w.Code != http.StatusBadRequest {
+		t.Errorf("oversized size status = %d, want 400", w.Code)
+	}
+
+	// One request holding 3/4 of the byte budget leaves no room for another
+	// 512KB, and a second holder uses up the concurrency limit.
+	release, _ := admit.tryAcquire(1, 768<<10)
+	w := get("/pooled?size=524288")
+	if w.Code != http.StatusServiceUnavailable || w.Header().Get("Retry-After") == "" {
+		t.Errorf("over byte budget = %d, Retry-After %q; want 503 with Retry-After", w.Code, w.Header().Get("Retry-After"))
+	}
+	release2, _ := admit.tryAcquire(1, 0)
+	w = get("/pooled?size=16")
+	if w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") == "" {
+		t.Errorf("over concurrency limit = %d, Retry-After %q; want 429 with Retry-After", w.Code, w.Header().Get("Retry-After"))
+	}
+	release2()
+	// The json workload holds far more than its input: 16KB reserves about
+	// 288KB, more than the 256KB left while the first holder runs.
+	if w := get("/json-naive?size=16384"); w.Code != http.StatusServiceUnavailable || w.Header().Get("Retry-After") == "" {
+		t.Errorf("json over the remaining budget = %d, Retry-After %q; want 503 with Retry-After", w.Code, w.Header().Get("Retry-After"))
+	}
+	release()
+	if w := get("/pooled?size=524288"); w.Code != http.StatusOK {
+		t.Errorf("status after release = %d, want 200", w.Code)
+	}
+	// 128KB of json reserves about 2MB, over the whole 1MB budget, so no
+	// retry can succeed.
+	if w := get("/json-naive?size=131072"); w.Code != http.StatusRequestEntityTooLarge || w.Header().Get("Retry-After") != "" {
+		t.Errorf("json over the whole budget = %d, Retry-After %q; want 413 without Retry-After", w.Code, w.Header().Get("Retry-After"))
+	}
+
+	// Load runs reserve a slot and a footprint per worker for their whole
+	// run; one that can never fit is a 400, one that does not fit now a 429.
+	post := func(target string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest("POST", target, nil))
+		return w
+	}
+	if w := post("/experiment/run?size=1024&concurrency=3&duration=100ms"); w.Code != http.StatusBadRequest {
+		t.Errorf("experiment over MaxInFlight = %d, want 400", w.Code)
+	}
+	if w := post("/experiment/gogc?values=100&size=1048576&concurrency=2&duration=100ms"); w.Code != http.StatusBadRequest {
+		t.Errorf("sweep over the byte budget = %d, want 400", w.Code)
+	}
+	if w := post("/experiment/run?processors=gzip-naive&size=1024&concurrency=1&duration=100ms"); w.Code != http.StatusBadRequest {
+		t.Errorf("experiment reserving gzip state past the budget = %d, want 400", w.Code)
+	}
+	release, _ = admit.tryAcquire(1, 768<<10)
+	if w := post("/experiment/run?size=524288&concurrency=1&duration=100ms"); w.Code != http.StatusServiceUnavailable {
+		t.Errorf("experiment while a request holds the bytes = %d, want 503", w.Code)
+	}
+	if w := post("/experiment/gogc?values=100&size=1024&concurrency=2&duration=100ms"); w.Code != http.StatusTooManyRequests {
+		t.Errorf("sweep while a request holds a slot = %d, want 429", w.Code)
+	}
+	release()
+	if loadRunning.Load() {
+		t.Error("loadRunning still set after rejected load runs")
+	}
+	if admit.inFlight.Load() != 0 || admit.bytes.Load() != 0 {
+		t.Errorf("admission leaked: %d in flight, %d bytes", admit.inFlight.Load(), admit.bytes.Load())
+	}
+
+	w = get("/metrics")
+	for _, want := range []string{
+		`http_requests_rejected_total{handler="naive",reason="size"}`,
+		`http_requests_rejected_total{handler="pooled",reason="bytes"}`,
+		`http_requests_rejected_total{handler="pooled",reason="concurrency"}`,
+		`http_requests_rejected_total{handler="json-naive",reason="bytes"}`,
+		`http_requests_rejected_total{handler="json-naive",reason="footprint"}`,
+		`http_requests_rejected_total{handler="experiment/run",reason="load"}`,
+		`http_requests_rejected_total{handler="experiment/gogc",reason="concurrency"}`,
+		"process_requests_in_flight 0",
+	} {
+		if !strings.Contains(w.Body.String(), want) {
+			t.Errorf("/metrics missing %s", want)
+		}
+	}
+}
+
+func TestAdmissionConfigFromEnv(t *testing.T) {
+	t.Setenv("MAX_SIZE", "2MiB")
+	t.Setenv("MAX_INFLIGHT", "7")
+	t.Setenv("MAX_INFLIGHT_BYTES", "off")
+	t.Setenv("MAX_BODY_BYTES", "-1")
+	cfg, def := admissionConfigFromEnv(), defaultAdmissionConfig()
+	if cfg.MaxSize != 2<<20 || cfg.MaxInFlight != 7 || cfg.MaxInFlightBytes != def.MaxInFlightBytes || cfg.MaxBodyBytes != def.MaxBodyBytes {
+		t.Errorf("config = %+v", cfg)
+	}
+}
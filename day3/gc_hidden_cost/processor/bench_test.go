@@ -0,0 +1,92 @@
+package processor
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"testing"
+)
+
+var benchStrategies = []struct {
+	name string
+	new  func() Processor
+}{
+	{"naive", func() Processor { return NewNaiveProcessor() }},
+	{"pooled", func() Processor { return NewPooledProcessor() }},
+	{"chan", func() Processor { return NewChanProcessor(DefaultFreeListSize) }},
+	{"sharded", func() Processor { return NewShardedProcessor(0) }},
+	{"arena", func() Processor { return NewArenaProcessor(DefaultSlabSize, DefaultArenaBatch) }},
+	{"stack", func() Processor { return NewStackProcessor() }},
+}
+
+var benchSizes = []int{512, 4096, 65536, 1 << 20}
+
+// BenchmarkProcessors runs every strategy at each size from parallel
+// goroutines, e.g.
+//
+//	go test -run '^$' -bench Processors -benchmem ./processor
+//	go test -run '^$' -bench 'Processors/size=65536' -benchmem ./processor
+func BenchmarkProcessors(b *testing.B) {
+	for _, size := range benchSizes {
+		for _, s := range benchStrategies {
+			b.Run(fmt.Sprintf("size=%d/%s", size, s.name), func(b *testing.B) {
+				p := s.new()
+				b.SetBytes(int64(size))
+				b.ReportAllocs()
+				b.RunParallel(func(pb *testing.PB) {
+					for pb.Next() {
+						if _, err := p.Process(size); err != nil {
+							b.Fatal(err)
+						}
+					}
+				})
+			})
+		}
+	}
+}
+
+// BenchmarkProcessTo is the same matrix through the streaming interface,
+// without the per-call result string.
+func BenchmarkProcessTo(b *testing.B) {
+	ctx := context.Background()
+	for _, size := range benchSizes {
+		for _, s := range benchStrategies {
+			b.Run(fmt.Sprintf("size=%d/%s", size, s.name), func(b *testing.B) {
+				sp := Adapt(s.new())
+				b.SetBytes(int64(size))
+				b.ReportAllocs()
+				b.RunParallel(func(pb *testing.PB) {
+					for pb.Next() {
+						if _, err := sp.ProcessTo(ctx, io.Discard, size); err != nil {
+							b.Fatal(err)
+						}
+					}
+				})
+			})
+		}
+	}
+}
+
+// BenchmarkWorkloads compares the naive and pooled variant of each
+// realistic workload at each size.
+func BenchmarkWorkloads(b *testing.B) {
+	ctx := context.Background()
+	for _, kind := range WorkloadKinds {
+		for _, size := range benchSizes {
+			for _, pooled := range []bool{false, true} {
+				p := workloadProcessor(b, kind, pooled)
+				b.Run(fmt.Sprintf("%s/size=%d/%s", kind, size, p.variant()), func(b *testing.B) {
+					b.SetBytes(int64(size))
+					b.ReportAllocs()
+					b.RunParallel(func(pb *testing.PB) {
+						for pb.Next() {
+							if _, err := p.ProcessTo(ctx, io.Discard, size); err != nil {
+								b.Fatal(err)
+							}
+						}
+					})
+				})
+			}
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package processor
+
+import (
+	"context"
+	"errors"
+	"io"
+)
+
+// ErrBodyTooLarge is returned by ProcessBody when the body exceeds limit.
+var ErrBodyTooLarge = errors.New("request body too large")
+
+// BodyProcessor transforms a request body: it reads at most limit bytes
+// from r, transforms them and writes the result to w. hint is the expected
+// length (an HTTP Content-Length), or -1 when unknown.
+type BodyProcessor interface {
+	ProcessBody(ctx context.Context, w io.Writer, r io.Reader, hint, limit int64) (int64, error)
+}
+
+// transformBody upper-cases ASCII letters in place, a stand-in for the
+// parsing or rewriting a real handler does with a body.
+func transformBody(buf []byte) {
+	for i, b := range buf {
+		if 'a' <= b && b <= 'z' {
+			buf[i] = b - ('a' - 'A')
+		}
+	}
+}
+
+// ProcessBody reads the whole body with io.ReadAll, which starts from a
+// small buffer and reallocates as it grows: the usual way handlers read
+// bodies, and a major source of garbage.
+func (p *NaiveProcessor) ProcessBody(ctx context.Context, w io.Writer, r io.Reader, hint, limit int64) (int64, error) {
+	buf, err := io.ReadAll(io.LimitReader(r, limit+1))
+	if err != nil {
+		return 0, err
+	}
+	if int64(len(buf)) > limit {
+		return 0, ErrBodyTooLarge
+	}
+	return writeTransformed(ctx, w, buf)
+}
+
+// ProcessBody reads the body into pooled buffers. With a length hint it
+// reads straight into a buffer of the right class; otherwise it starts
+// small and moves to the next class up whenever the buffer fills, returning
+// each outgrown buffer to the pool.
+func (p *PooledProcessor) ProcessBody(ctx context.Context, w io.Writer, r io.Reader, hint, limit int64) (int64, error) {
+	bp, err := p.pool.ReadAll(r, hint, limit)
+	if err != nil {
+		return 0, err
+	}
+	defer p.pool.Put(bp)
+	return writeTransformed(ctx, w, *bp)
+}
+
+func writeTransformed(ctx context.Context, w io.Writer, buf []byte) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	transformBody(buf)
+	n, err := w.Write(buf)
+	return int64(n), err
+}
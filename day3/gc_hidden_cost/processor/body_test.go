@@ -0,0 +1,58 @@
+package processor
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestProcessBody(t *testing.T) {
+	for name, p := range map[string]BodyProcessor{"naive": NewNaiveProcessor(), "pooled": NewPooledProcessor()} {
+		for _, size := range []int{0, 5, 4096, 5000, 70000, DefaultMaxClassSize + 10} {
+			body := strings.Repeat("abcXyz", size/6+1)[:size]
+			want := strings.ToUpper(body)
+			for _, hint := range []int64{int64(size), -1} {
+				var out bytes.Buffer
+				r := iotest.HalfReader(strings.NewReader(body))
+				n, err := p.ProcessBody(context.Background(), &out, r, hint, 2<<20)
+				if err != nil || n != int64(size) || out.String() != want {
+					t.Errorf("%s(size %d, hint %d): n=%d err=%v match=%v", name, size, hint, n, err, out.String() == want)
+				}
+			}
+		}
+		_, err := p.ProcessBody(context.Background(), io.Discard, strings.NewReader(strings.Repeat("x", 101)), -1, 100)
+		if !errors.Is(err, ErrBodyTooLarge) {
+			t.Errorf("%s: over-limit body returned %v", name, err)
+		}
+		_, err = p.ProcessBody(context.Background(), io.Discard, iotest.ErrReader(io.ErrUnexpectedEOF), -1, 100)
+		if !errors.Is(err, io.ErrUnexpectedEOF) {
+			t.Errorf("%s: read error returned %v", name, err)
+		}
+	}
+}
+
+func TestPooledProcessBodyReusesBuffers(t *testing.T) {
+	if raceEnabled {
+		t.Skip("sync.Pool drops items under the race detector")
+	}
+	p := NewPooledProcessor()
+	body := bytes.Repeat([]byte("a"), 20000)
+	r := bytes.NewReader(body)
+	ctx := context.Background()
+	for _, hint := range []int64{int64(len(body)), -1} {
+		allocs := testing.AllocsPerRun(50, func() {
+			r.Reset(body)
+			p.ProcessBody(ctx, io.Discard, r, hint, 1<<20)
+		})
+		if allocs > 0 {
+			t.Errorf("hint %d: pooled ProcessBody allocated %.1f times per call", hint, allocs)
+		}
+	}
+	if st := p.Stats(); st.Discards != 0 || st.Puts != st.Gets {
+		t.Errorf("buffers leaked or dropped: %+v", st)
+	}
+}
@@ -0,0 +1,185 @@
+package processor
+
+import (
+	"fmt"
+	"io"
+	"math/bits"
+	"sync"
+	"sync/atomic"
+)
+
+// Default size classes for PooledProcessor: 4KB up to 1MB in powers of two.
+const (
+	DefaultMinClassSize = 4 << 10
+	DefaultMaxClassSize = 1 << 20
+)
+
+// ClassStats reports how one size class of a BufferPool has been used.
+// Misses are Gets the pool could not satisfy, i.e. calls to New.
+type ClassStats struct {
+	Size   int    `json:"size"`
+	Hits   uint64 `json:"hits"`
+	Misses uint64 `json:"misses"`
+	Puts   uint64 `json:"puts"`
+}
+
+// PoolStats is a snapshot of a BufferPool's counters. The totals sum the
+// classes; Oversize counts requests larger than the biggest class, which
+// are allocated directly, and Discards counts Puts that were dropped
+// because the buffer did not fit a class exactly.
+type PoolStats struct {
+	Classes  []ClassStats `json:"classes"`
+	Gets     uint64       `json:"gets"`
+	Hits     uint64       `json:"hits"`
+	Misses   uint64       `json:"misses"`
+	Puts     uint64       `json:"puts"`
+	Oversize uint64       `json:"oversize"`
+	Discards uint64       `json:"discards"`
+}
+
+// ReuseRate is the fraction of all buffer requests, oversize included,
+// served by a pooled buffer. It is 0 before the first request.
+func (s PoolStats) ReuseRate() float64 {
+	if total := s.Gets + s.Oversize; total > 0 {
+		return float64(s.Hits) / float64(total)
+	}
+	return 0
+}
+
+type sizeClass struct {
+	size   int
+	pool   sync.Pool
+	gets   atomic.Uint64
+	misses atomic.Uint64
+	puts   atomic.Uint64
+}
+
+// BufferPool keeps one sync.Pool per power-of-two size class so a 64KB
+// request never evicts (or is served by) a 4KB buffer. Buffers above the
+// largest class are not pooled, which caps what the pool can retain.
+type BufferPool struct {
+	minShift int
+	classes  []*sizeClass
+	oversize atomic.Uint64
+	discards atomic.Uint64
+}
+
+// NewBufferPool returns a pool with classes from minSize to maxSize, both
+// rounded up to powers of two.
+func NewBufferPool(minSize, maxSize int) *BufferPool {
+	if minSize <= 0 || maxSize < minSize {
+		panic(fmt.Sprintf("processor: invalid buffer pool bounds %d-%d", minSize, maxSize))
+	}
+	p := &BufferPool{minShift: bits.Len(uint(minSize - 1))}
+	for shift := p.minShift; shift <= bits.Len(uint(maxSize-1)); shift++ {
+		c := &sizeClass{size: 1 << shift}
+		c.pool.New = func() any {
+			c.misses.Add(1)
+			buf := make([]byte, c.size)
+			return &buf
+		}
+		p.classes = append(p.classes, c)
+	}
+	return p
+}
+
+// class returns the smallest class that fits size, or nil if none does.
+func (p *BufferPool) class(size int) *sizeClass {
+	if size <= 0 {
+		return p.classes[0]
+	}
+	i := bits.Len(uint(size-1)) - p.minShift
+	if i < 0 {
+		i = 0
+	}
+	if i >= len(p.classes) {
+		return nil
+	}
+	return p.classes[i]
+}
+
+// Get returns a buffer of length size. Its capacity is the class size, and
+// its contents are whatever the previous user left. The pointer (rather
+// than the slice) is what travels through sync.Pool, so a Get/Put round
+// trip does not allocate.
+func (p *BufferPool) Get(size int) *[]byte {
+	c := p.class(size)
+	if c == nil {
+		p.oversize.Add(1)
+		buf := make([]byte, size)
+		return &buf
+	}
+	c.gets.Add(1)
+	bp := c.pool.Get().(*[]byte)
+	*bp = (*bp)[:size]
+	return bp
+}
+
+// Put returns a buffer to the class matching its capacity. Buffers that
+// did not come from a class (oversize, or resliced to another capacity)
+// are dropped.
+func (p *BufferPool) Put(bp *[]byte) {
+	c := p.class(cap(*bp))
+	if c == nil || c.size != cap(*bp) {
+		p.discards.Add(1)
+		return
+	}
+	c.puts.Add(1)
+	*bp = (*bp)[:0]
+	c.pool.Put(bp)
+}
+
+// ReadAll reads r to EOF into a pooled buffer and returns it; the caller
+// must Put it back. hint sizes the first buffer (use -1 if unknown). When
+// a buffer fills up, its contents move to one twice the size and the old
+// one goes back to the pool, so only the final buffer stays checked out.
+// More than limit bytes is ErrBodyTooLarge; the limit is applied here
+// rather than with io.LimitReader, which would allocate on every call.
+func (p *BufferPool) ReadAll(r io.Reader, hint, limit int64) (*[]byte, error) {
+	first := p.classes[0].size
+	if hint >= 0 && hint < int64(p.classes[len(p.classes)-1].size) {
+		// One spare byte lets an exact hint reach EOF without growing.
+		first = max(first, int(hint)+1)
+	}
+	bp := p.Get(first)
+	*bp = (*bp)[:0]
+	for {
+		buf := *bp
+		if len(buf) == cap(buf) {
+			next := p.Get(2 * cap(buf))
+			*next = append((*next)[:0], buf...)
+			p.Put(bp)
+			bp, buf = next, *next
+		}
+		// Read at most one byte past the limit, enough to detect overflow.
+		room := buf[len(buf):min(int64(cap(buf)), limit+1)]
+		n, err := r.Read(room)
+		*bp = buf[:len(buf)+n]
+		if int64(len(*bp)) > limit {
+			p.Put(bp)
+			return nil, ErrBodyTooLarge
+		}
+		if err == io.EOF {
+			return bp, nil
+		}
+		if err != nil {
+			p.Put(bp)
+			return nil, err
+		}
+	}
+}
+
+// Stats returns the per-class counters and their totals.
+func (p *BufferPool) Stats() PoolStats {
+	st := PoolStats{Oversize: p.oversize.Load(), Discards: p.discards.Load()}
+	for _, c := range p.classes {
+		gets, misses := c.gets.Load(), c.misses.Load()
+		cs := ClassStats{Size: c.size, Hits: gets - min(misses, gets), Misses: misses, Puts: c.puts.Load()}
+		st.Classes = append(st.Classes, cs)
+		st.Gets += gets
+		st.Hits += cs.Hits
+		st.Misses += cs.Misses
+		st.Puts += cs.Puts
+	}
+	return st
+}
@@ -0,0 +1,100 @@
+package processor
+
+import "testing"
+
+func TestBufferPoolSizeClasses(t *testing.T) {
+	p := NewBufferPool(4096, 65536)
+	for _, c := range []struct{ size, wantCap int }{{1, 4096}, {4096, 4096}, {4097, 8192}, {65536, 65536}} {
+		bp := p.Get(c.size)
+		if len(*bp) != c.size || cap(*bp) != c.wantCap {
+			t.Errorf("Get(%d): len %d cap %d, want cap %d", c.size, len(*bp), cap(*bp), c.wantCap)
+		}
+		p.Put(bp)
+	}
+	big := p.Get(65537)
+	p.Put(big)
+	foreign := make([]byte, 5000)
+	p.Put(&foreign)
+
+	st := p.Stats()
+	if len(st.Classes) != 5 || st.Classes[0].Size != 4096 || st.Classes[4].Size != 65536 {
+		t.Fatalf("classes = %+v", st.Classes)
+	}
+	if st.Oversize != 1 {
+		t.Errorf("oversize = %d, want 1", st.Oversize)
+	}
+	// The second 4KB-class Get reuses the first one's buffer, unless the
+	// race detector dropped the Put.
+	if c := st.Classes[0]; c.Hits+c.Misses != 2 || (!raceEnabled && c.Hits != 1) {
+		t.Errorf("4KB class = %+v, want 1 miss then 1 hit", c)
+	}
+}
+
+// TestPooledProcessorMixedSizes is the workload that thrashed the old single
+// pool: alternating 4KB and 64KB requests should each reuse their own class.
+func TestPooledProcessorMixedSizes(t *testing.T) {
+	if raceEnabled {
+		t.Skip("sync.Pool drops items under the race detector")
+	}
+	p := NewPooledProcessor()
+	allocs := testing.AllocsPerRun(100, func() {
+		bp := p.pool.Get(4096)
+		p.pool.Put(bp)
+		bp = p.pool.Get(65536)
+		p.pool.Put(bp)
+	})
+	if allocs > 0 {
+		t.Errorf("mixed-size Get/Put allocated %.1f times per run", allocs)
+	}
+	for _, c := range p.Stats().Classes {
+		if (c.Size == 4096 || c.Size == 65536) && c.Hits < 90 {
+			t.Errorf("class %d: %d hits, %d misses", c.Size, c.Hits, c.Misses)
+		}
+	}
+}
+
+func TestBufferPoolCountsPutsAndDiscards(t *testing.T) {
+	p := NewBufferPool(4096, 8192)
+	p.Put(p.Get(100))
+	p.Put(p.Get(9000))
+	small := make([]byte, 10)
+	p.Put(&small)
+	st := p.Stats()
+	if st.Gets != 1 || st.Misses != 1 || st.Puts != 1 || st.Oversize != 1 || st.Discards != 2 {
+		t.Errorf("stats = %+v", st)
+	}
+	if NewBufferPool(4096, 8192).Stats().ReuseRate() != 0 {
+		t.Error("unused pool has non-zero reuse rate")
+	}
+}
+
+// TestPooledProcessorSteadyStateReuse drives mixed sizes from several
+// goroutines; once each class is warm, nearly every Get should be a hit.
+func TestPooledProcessorSteadyStateReuse(t *testing.T) {
+	if raceEnabled {
+		t.Skip("sync.Pool drops items under the race detector")
+	}
+	var p Processor = NewPooledProcessor()
+	done := make(chan struct{})
+	for g := 0; g < 4; g++ {
+		go func() {
+			defer func() { done <- struct{}{} }()
+			for i := 0; i < 2000; i++ {
+				if _, err := p.Process([]int{512, 4096, 20000, 65536}[i%4]); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}()
+	}
+	for g := 0; g < 4; g++ {
+		<-done
+	}
+	st := p.(PoolReporter).Stats()
+	if st.Gets != 8000 || st.Puts != 8000 || st.Discards != 0 {
+		t.Errorf("gets/puts/discards = %d/%d/%d, want 8000/8000/0", st.Gets, st.Puts, st.Discards)
+	}
+	if rate := st.ReuseRate(); rate < 0.9 {
+		t.Errorf("reuse rate = %.3f (hits %d, misses %d), want >= 0.9", rate, st.Hits, st.Misses)
+	}
+}
@@ -0,0 +1,71 @@
+package processor
+
+import (
+	"context"
+	"fmt"
+	"io"
+)
+
+// DefaultFreeListSize is the number of buffers ChanProcessor retains.
+const DefaultFreeListSize = 64
+
+// ChanProcessor keeps a bounded free list in a buffered channel. Unlike
+// sync.Pool it never loses buffers to GC, but it also never shrinks, and
+// every Get and Put goes through the channel's lock.
+type ChanProcessor struct {
+	free     chan *[]byte
+	counters reuseCounters
+}
+
+func NewChanProcessor(capacity int) *ChanProcessor {
+	return &ChanProcessor{free: make(chan *[]byte, capacity)}
+}
+
+func (p *ChanProcessor) Process(size int) (string, error) {
+	bp := p.get(size)
+	fill(*bp)
+	p.put(bp)
+	return fmt.Sprintf("Chan: Processed %d bytes", size), nil
+}
+
+func (p *ChanProcessor) ProcessTo(ctx context.Context, w io.Writer, size int) (int64, error) {
+	bp := p.get(size)
+	defer p.put(bp)
+	return fillTo(ctx, w, *bp)
+}
+
+func (p *ChanProcessor) get(size int) *[]byte {
+	p.counters.gets.Add(1)
+	select {
+	case bp := <-p.free:
+		if cap(*bp) >= size {
+			*bp = (*bp)[:size]
+			return bp
+		}
+		p.counters.discards.Add(1)
+	default:
+	}
+	p.counters.misses.Add(1)
+	buf := make([]byte, size, max(size, DefaultMinClassSize))
+	return &buf
+}
+
+// put retains bp unless it is larger than DefaultMaxClassSize or the free
+// list is full.
+func (p *ChanProcessor) put(bp *[]byte) {
+	if cap(*bp) > DefaultMaxClassSize {
+		p.counters.discards.Add(1)
+		return
+	}
+	select {
+	case p.free <- bp:
+		p.counters.puts.Add(1)
+	default:
+		p.counters.discards.Add(1)
+	}
+}
+
+// Stats reports free-list reuse; it has no size classes.
+func (p *ChanProcessor) Stats() PoolStats {
+	return p.counters.stats()
+}
@@ -1,8 +1,9 @@
 package processor
 
 import (
+	"context"
 	"fmt"
-	"sync"
+	"io"
 )
 
 // Processor defines the interface for our processing logic.
@@ -10,6 +11,29 @@ type Processor interface {
 	Process(size int) (string, error)
 }
 
+// PoolReporter is implemented by processors that reuse buffers, so callers
+// can check whether the reuse is actually happening.
+type PoolReporter interface {
+	Stats() PoolStats
+}
+
+// Footprinter is implemented by processors whose calls hold more memory
+// than the requested size, so admission control can reserve what a call
+// really uses rather than size alone.
+type Footprinter interface {
+	Footprint(size int) int64
+}
+
+// Footprint estimates the bytes one call of p for size holds: p's own
+// estimate if it implements Footprinter, otherwise size, which is what the
+// buffer strategies allocate.
+func Footprint(p Processor, size int) int64 {
+	if f, ok := p.(Footprinter); ok {
+		return f.Footprint(size)
+	}
+	return int64(size)
+}
+
 // NaiveProcessor allocates a new byte slice for each request.
 type NaiveProcessor struct{}
 
@@ -25,51 +49,43 @@ func (p *NaiveProcessor) Process(size int) (string, error) {
 	return fmt.Sprintf("Naive: Processed %d bytes", size), nil
 }
 
-// PooledProcessor uses sync.Pool to reuse byte slices.
+func (p *NaiveProcessor) ProcessTo(ctx context.Context, w io.Writer, size int) (int64, error) {
+	return fillTo(ctx, w, make([]byte, size))
+}
+
+// PooledProcessor reuses buffers from a BufferPool with power-of-two size
+// classes, so mixed request sizes each reuse buffers of their own class
+// instead of discarding one shared pool's undersized buffers.
 type PooledProcessor struct {
-	pool sync.Pool
+	pool *BufferPool
 }
 
 func NewPooledProcessor() *PooledProcessor {
-	return &PooledProcessor{
-		pool: sync.Pool{
-			New: func() interface{} {
-				// Initial capacity for new buffers. Will be adjusted if needed.
-				// We return a slice with len 0 but capacity 4KB as a starting point.
-				return make([]byte, 0, 4096)
-			},
-		},
-	}
+	return &PooledProcessor{pool: NewBufferPool(DefaultMinClassSize, DefaultMaxClassSize)}
 }
 
 func (p *PooledProcessor) Process(size int) (string, error) {
-	// Get a buffer from the pool.
-	// It's returned as an interface{}, so we need a type assertion.
-	// If the pool is empty, p.pool.New() will be called.
-	bufIface := p.pool.Get()
-	buf, ok := bufIface.([]byte)
-	if !ok {
-		return "", fmt.Errorf("failed to assert type from sync.Pool")
-	}
-
-	// Ensure the buffer has enough capacity. If not, create a new one.
-	if cap(buf) < size {
-		// The retrieved buffer is too small, discard it and create a new one.
-		// Note: The old buffer will be GC'd if no other references exist.
-		buf = make([]byte, size)
-	} else {
-		// The buffer has sufficient capacity, re-slice it to the desired length.
-		buf = buf[:size]
-	}
+	// Get a buffer of at least size bytes from the matching class.
+	bp := p.pool.Get(size)
+	buf := *bp
 
 	for i := 0; i < size; i++ {
 		buf[i] = byte(i % 256) // Dummy write
 	}
 
-	// Return the buffer to the pool.
-	// IMPORTANT: Reset the length to 0 to prevent retaining stale data and
-	// to allow subsequent Get() calls to correctly use len(0) if needed.
-	p.pool.Put(buf[:0])
+	// Return the buffer to its class. Oversize buffers are dropped by Put.
+	p.pool.Put(bp)
 
 	return fmt.Sprintf("Pooled: Processed %d bytes", size), nil
 }
+
+func (p *PooledProcessor) ProcessTo(ctx context.Context, w io.Writer, size int) (int64, error) {
+	bp := p.pool.Get(size)
+	defer p.pool.Put(bp)
+	return fillTo(ctx, w, *bp)
+}
+
+// Stats reports the buffer pool's hits, misses, puts and discards.
+func (p *PooledProcessor) Stats() PoolStats {
+	return p.pool.Stats()
+}
@@ -0,0 +1,7 @@
+//go:build race
+
+package processor
+
+// The race detector makes sync.Pool drop a share of Puts on purpose, so
+// reuse-rate thresholds only hold in normal builds.
+const raceEnabled = true
@@ -0,0 +1,28 @@
+package processor
+
+import "sync/atomic"
+
+// reuseCounters tracks a free list without size classes: a hit is a reused
+// buffer that was big enough, a miss allocates, and a discard drops a
+// buffer that was too small to serve the request or did not fit back in.
+type reuseCounters struct {
+	gets, misses, puts, discards atomic.Uint64
+}
+
+func (c *reuseCounters) stats() PoolStats {
+	gets, misses := c.gets.Load(), c.misses.Load()
+	return PoolStats{
+		Gets:     gets,
+		Hits:     gets - min(misses, gets),
+		Misses:   misses,
+		Puts:     c.puts.Load(),
+		Discards: c.discards.Load(),
+	}
+}
+
+// fill writes the same dummy pattern as the original processors.
+func fill(buf []byte) {
+	for i := range buf {
+		buf[i] = byte(i % 256)
+	}
+}
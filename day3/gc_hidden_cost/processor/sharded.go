@@ -0,0 +1,108 @@
+package processor
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"runtime"
+	"sync/atomic"
+)
+
+// shardSlots is how many buffers each shard can hold.
+const shardSlots = 8
+
+// shard is a fixed set of slots claimed with atomic swaps, so no locks are
+// taken and there is no ABA problem as with a linked free list. Each shard
+// keeps its own counters, and the padding keeps neighbouring shards off the
+// same cache line.
+type shard struct {
+	slots    [shardSlots]atomic.Pointer[[]byte]
+	counters reuseCounters
+	_        [64]byte
+}
+
+// ShardedProcessor spreads buffers across shards so concurrent requests
+// rarely touch the same slots. Go does not expose the current P, so each
+// request picks a shard at random from math/rand/v2, whose generator is
+// per thread; there is no counter every request has to bump. This is not
+// sync.Pool's per-P cache, since two goroutines on one P can pick different
+// shards and two on different Ps the same one, but it needs no shared state
+// and keeps no GC-time clearing. A buffer goes back to the shard it came from.
+type ShardedProcessor struct {
+	shards []shard
+}
+
+// NewShardedProcessor returns a processor with n shards; n <= 0 means
+// one per GOMAXPROCS.
+func NewShardedProcessor(n int) *ShardedProcessor {
+	if n <= 0 {
+		n = runtime.GOMAXPROCS(0)
+	}
+	return &ShardedProcessor{shards: make([]shard, n)}
+}
+
+func (p *ShardedProcessor) Process(size int) (string, error) {
+	s := p.shard()
+	bp := p.get(s, size)
+	fill(*bp)
+	p.put(s, bp)
+	return fmt.Sprintf("Sharded: Processed %d bytes", size), nil
+}
+
+func (p *ShardedProcessor) ProcessTo(ctx context.Context, w io.Writer, size int) (int64, error) {
+	s := p.shard()
+	bp := p.get(s, size)
+	defer p.put(s, bp)
+	return fillTo(ctx, w, *bp)
+}
+
+func (p *ShardedProcessor) shard() *shard {
+	return &p.shards[rand.IntN(len(p.shards))]
+}
+
+func (p *ShardedProcessor) get(s *shard, size int) *[]byte {
+	s.counters.gets.Add(1)
+	for i := range s.slots {
+		bp := s.slots[i].Load()
+		if bp == nil || !s.slots[i].CompareAndSwap(bp, nil) {
+			continue
+		}
+		if cap(*bp) >= size {
+			*bp = (*bp)[:size]
+			return bp
+		}
+		s.counters.discards.Add(1)
+	}
+	s.counters.misses.Add(1)
+	buf := make([]byte, size, max(size, DefaultMinClassSize))
+	return &buf
+}
+
+// put stores bp in the first free slot of s, dropping it if the shard is
+// full or the buffer is larger than DefaultMaxClassSize.
+func (p *ShardedProcessor) put(s *shard, bp *[]byte) {
+	if cap(*bp) <= DefaultMaxClassSize {
+		for i := range s.slots {
+			if s.slots[i].CompareAndSwap(nil, bp) {
+				s.counters.puts.Add(1)
+				return
+			}
+		}
+	}
+	s.counters.discards.Add(1)
+}
+
+// Stats reports reuse summed across all shards.
+func (p *ShardedProcessor) Stats() PoolStats {
+	var total PoolStats
+	for i := range p.shards {
+		st := p.shards[i].counters.stats()
+		total.Gets += st.Gets
+		total.Hits += st.Hits
+		total.Misses += st.Misses
+		total.Puts += st.Puts
+		total.Discards += st.Discards
+	}
+	return total
+}
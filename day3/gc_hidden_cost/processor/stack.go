@@ -0,0 +1,53 @@
+package processor
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync/atomic"
+)
+
+// StackArraySize is the largest request StackProcessor serves from a
+// fixed-size array in its own stack frame.
+const StackArraySize = 8 << 10
+
+// StackProcessor uses a fixed-size local array for small requests. The
+// array never escapes, so the compiler keeps it on the goroutine stack and
+// there is nothing for the GC to track; larger requests fall back to a heap
+// allocation.
+type StackProcessor struct {
+	fallbacks atomic.Uint64
+}
+
+func NewStackProcessor() *StackProcessor {
+	return &StackProcessor{}
+}
+
+func (p *StackProcessor) Process(size int) (string, error) {
+	if size > StackArraySize {
+		p.fallbacks.Add(1)
+		fill(make([]byte, size))
+		return fmt.Sprintf("Stack: Processed %d bytes (heap fallback)", size), nil
+	}
+	var arr [StackArraySize]byte
+	fill(arr[:size])
+	return fmt.Sprintf("Stack: Processed %d bytes", size), nil
+}
+
+// ProcessTo streams the array to w. Handing it to an arbitrary io.Writer
+// makes the array escape, since the compiler cannot prove w does not keep
+// it, so unlike Process this allocates on every call. That is the price of
+// the stack strategy once results have to leave the function.
+func (p *StackProcessor) ProcessTo(ctx context.Context, w io.Writer, size int) (int64, error) {
+	if size > StackArraySize {
+		p.fallbacks.Add(1)
+		return fillTo(ctx, w, make([]byte, size))
+	}
+	var arr [StackArraySize]byte
+	return fillTo(ctx, w, arr[:size])
+}
+
+// Fallbacks is the number of requests too large for the stack array.
+func (p *StackProcessor) Fallbacks() uint64 {
+	return p.fallbacks.Load()
+}
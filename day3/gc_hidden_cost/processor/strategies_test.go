@@ -0,0 +1,102 @@
+package processor
+
+import (
+	"fmt"
+	"runtime"
+	"sync"
+	"testing"
+)
+
+func TestFreeListProcessorsReuse(t *testing.T) {
+	for name, p := range map[string]interface {
+		Processor
+		PoolReporter
+	}{
+		"chan":    NewChanProcessor(4),
+		"sharded": NewShardedProcessor(2),
+	} {
+		for i := 0; i < 100; i++ {
+			if _, err := p.Process(4096); err != nil {
+				t.Fatal(err)
+			}
+		}
+		st := p.Stats()
+		if st.Gets != 100 || st.ReuseRate() < 0.9 {
+			t.Errorf("%s: stats = %+v, reuse %.2f", name, st, st.ReuseRate())
+		}
+		// A larger request cannot use the retained 4KB buffers.
+		p.Process(65536)
+		if after := p.Stats(); after.Discards == st.Discards {
+			t.Errorf("%s: too-small buffer was not discarded: %+v", name, after)
+		}
+	}
+}
+
+// TestArenaBuffersDoNotOverlap holds buffers across a batch boundary: a
+// slab must not be rewound while any of its buffers is still live.
+func TestArenaBuffersDoNotOverlap(t *testing.T) {
+	p := NewArenaProcessor(4096, 4)
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(id byte) {
+			defer wg.Done()
+			for i := 0; i < 500; i++ {
+				s, buf := p.alloc(256)
+				for j := range buf {
+					buf[j] = id
+				}
+				runtime.Gosched()
+				for j := range buf {
+					if buf[j] != id {
+						t.Errorf("goroutine %d: buffer overwritten by %d", id, buf[j])
+						p.release(s)
+						return
+					}
+				}
+				p.release(s)
+			}
+		}(byte(g + 1))
+	}
+	wg.Wait()
+	st := p.Stats()
+	// Every batch would need a fresh slab without rewinding.
+	if st.Requests != 4000 || st.Resets == 0 || st.SlabsMade > st.Requests/uint64(st.Batch)/4 {
+		t.Errorf("stats = %+v", st)
+	}
+	if s, buf := p.alloc(8192); s != nil || len(buf) != 8192 || p.Stats().Oversize != 1 {
+		t.Error("oversize request was not allocated directly")
+	}
+}
+
+// stackTestSize is a variable so formatting it boxes the int at run time,
+// as Process does.
+var stackTestSize = 4096
+
+func TestStackProcessor(t *testing.T) {
+	p := NewStackProcessor()
+	// Process still allocates its result string; the array must add nothing
+	// on top of that, while the heap fallback adds its buffer.
+	size := stackTestSize
+	format := testing.AllocsPerRun(100, func() {
+		_ = fmt.Sprintf("Stack: Processed %d bytes", size)
+	})
+	stack := testing.AllocsPerRun(100, func() {
+		p.Process(size)
+	})
+	if stack != format {
+		t.Errorf("Process(%d) allocated %.1f times per run, formatting alone %.1f", size, stack, format)
+	}
+	fallback := testing.AllocsPerRun(100, func() {
+		p.Process(StackArraySize + 1)
+	})
+	if fallback <= stack {
+		t.Errorf("heap fallback allocated %.1f times per run, stack path %.1f", fallback, stack)
+	}
+	p = NewStackProcessor()
+	p.Process(StackArraySize)
+	p.Process(StackArraySize + 1)
+	if p.Fallbacks() != 1 {
+		t.Errorf("fallbacks = %d, want 1", p.Fallbacks())
+	}
+}
@@ -0,0 +1,65 @@
+package processor
+
+import (
+	"context"
+	"io"
+)
+
+// streamChunk is how much ProcessTo fills and writes between context
+// checks. It is a multiple of 256 so the dummy pattern lines up across
+// chunks and matches what Process writes.
+const streamChunk = 32 << 10
+
+// StreamProcessor is the context-aware successor to Processor. ProcessTo
+// writes the size processed bytes to w as they are produced and returns how
+// many were written. It stops early with ctx.Err() once ctx is done, so a
+// client disconnect or deadline abandons large requests part way. Nothing
+// is formatted, so a pooled implementation can run without allocating.
+// Implementations must not retain w's argument after Write returns, and w
+// must not retain it either, as the io.Writer contract requires.
+type StreamProcessor interface {
+	ProcessTo(ctx context.Context, w io.Writer, size int) (int64, error)
+}
+
+// Adapt returns p as a StreamProcessor. Processors that only implement
+// Process are wrapped: the wrapper checks ctx before calling Process and
+// writes its result string to w.
+func Adapt(p Processor) StreamProcessor {
+	if sp, ok := p.(StreamProcessor); ok {
+		return sp
+	}
+	return legacyProcessor{p}
+}
+
+type legacyProcessor struct{ p Processor }
+
+func (l legacyProcessor) ProcessTo(ctx context.Context, w io.Writer, size int) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	result, err := l.p.Process(size)
+	if err != nil {
+		return 0, err
+	}
+	n, err := io.WriteString(w, result)
+	return int64(n), err
+}
+
+// fillTo fills buf with the dummy pattern a chunk at a time, writing each
+// chunk to w and checking ctx in between.
+func fillTo(ctx context.Context, w io.Writer, buf []byte) (int64, error) {
+	var written int64
+	for off := 0; off < len(buf); off += streamChunk {
+		if err := ctx.Err(); err != nil {
+			return written, err
+		}
+		chunk := buf[off:min(off+streamChunk, len(buf))]
+		fill(chunk)
+		n, err := w.Write(chunk)
+		written += int64(n)
+		if err != nil {
+			return written, err
+		}
+	}
+	return written, nil
+}
@@ -0,0 +1,96 @@
+package processor
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func allStreamProcessors() map[string]StreamProcessor {
+	return map[string]StreamProcessor{
+		"naive":   NewNaiveProcessor(),
+		"pooled":  NewPooledProcessor(),
+		"chan":    NewChanProcessor(4),
+		"sharded": NewShardedProcessor(2),
+		"arena":   NewArenaProcessor(DefaultSlabSize, DefaultArenaBatch),
+		"stack":   NewStackProcessor(),
+	}
+}
+
+func TestProcessToWritesPattern(t *testing.T) {
+	for name, p := range allStreamProcessors() {
+		for _, size := range []int{1, 4096, 100000} {
+			var out bytes.Buffer
+			n, err := p.ProcessTo(context.Background(), &out, size)
+			if err != nil || n != int64(size) || out.Len() != size {
+				t.Fatalf("%s(%d): n=%d len=%d err=%v", name, size, n, out.Len(), err)
+			}
+			for i, b := range out.Bytes() {
+				if b != byte(i%256) {
+					t.Fatalf("%s(%d): byte %d = %d, want %d", name, size, i, b, byte(i%256))
+				}
+			}
+		}
+	}
+}
+
+// cancelAfterWrite cancels the context on its first Write, like a client
+// that disconnects after the first chunk.
+type cancelAfterWrite struct {
+	cancel  context.CancelFunc
+	written int
+}
+
+func (c *cancelAfterWrite) Write(p []byte) (int, error) {
+	c.written += len(p)
+	c.cancel()
+	return len(p), nil
+}
+
+func TestProcessToStopsOnCancel(t *testing.T) {
+	for name, p := range allStreamProcessors() {
+		ctx, cancel := context.WithCancel(context.Background())
+		w := &cancelAfterWrite{cancel: cancel}
+		n, err := p.ProcessTo(ctx, w, 1<<20)
+		if !errors.Is(err, context.Canceled) || n != streamChunk || w.written != streamChunk {
+			t.Errorf("%s: n=%d written=%d err=%v, want one chunk then context.Canceled", name, n, w.written, err)
+		}
+	}
+}
+
+func TestPooledProcessToDoesNotAllocate(t *testing.T) {
+	if raceEnabled {
+		t.Skip("sync.Pool drops items under the race detector")
+	}
+	p := NewPooledProcessor()
+	ctx := context.Background()
+	allocs := testing.AllocsPerRun(100, func() {
+		p.ProcessTo(ctx, io.Discard, 65536)
+	})
+	if allocs > 0 {
+		t.Errorf("pooled ProcessTo allocated %.1f times per call", allocs)
+	}
+}
+
+type messageOnly struct{}
+
+func (messageOnly) Process(size int) (string, error) { return "done", nil }
+
+func TestAdaptWrapsLegacyProcessors(t *testing.T) {
+	if _, ok := Adapt(NewPooledProcessor()).(*PooledProcessor); !ok {
+		t.Error("Adapt wrapped a processor that already streams")
+	}
+	var out strings.Builder
+	sp := Adapt(messageOnly{})
+	if n, err := sp.ProcessTo(context.Background(), &out, 10); err != nil || n != 4 || out.String() != "done" {
+		t.Errorf("adapted ProcessTo = %d, %v, %q", n, err, out.String())
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := sp.ProcessTo(ctx, &out, 10); !errors.Is(err, context.Canceled) {
+		t.Errorf("adapted ProcessTo ignored cancellation: %v", err)
+	}
+}
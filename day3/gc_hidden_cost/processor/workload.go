@@ -0,0 +1,314 @@
+package processor
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io"
+	"sync"
+)
+
+// WorkloadKinds are the request-shaped workloads NewWorkloadProcessor
+// accepts.
+var WorkloadKinds = []string{"json", "gzip", "sha256", "varint"}
+
+// workloadState is the scratch state one call needs: encoders, hashers,
+// compressors and buffers. Its run method does the work for size bytes of
+// input and writes the output to w.
+type workloadState interface {
+	run(ctx context.Context, w io.Writer, size int) (int64, error)
+	// retained is roughly how many bytes the state keeps between calls.
+	retained() int
+}
+
+// WorkloadProcessor runs one workload kind. The naive variant builds a
+// fresh state for every call, as typical handler code does; the pooled
+// variant reuses states through a sync.Pool. The work itself is identical,
+// so any difference comes from allocation and GC alone.
+type WorkloadProcessor struct {
+	kind     string
+	pooled   bool
+	newState func() workloadState
+	states   sync.Pool
+	// footprint is roughly how many bytes one call allocates for size
+	// bytes of input, measured with the naive variant.
+	footprint func(size int) int64
+}
+
+// gzipWriterBytes is roughly the compressor state gzip.NewWriter allocates
+// at the default level.
+const gzipWriterBytes = 1<<20 + 64<<10
+
+// NewWorkloadProcessor returns the naive or pooled variant of kind, which
+// must be one of WorkloadKinds.
+func NewWorkloadProcessor(kind string, pooled bool) (*WorkloadProcessor, error) {
+	p := &WorkloadProcessor{kind: kind, pooled: pooled}
+	switch kind {
+	case "json":
+		// Two decoded orders, the encoding and the decoder's copies come
+		// to about 14x the input.
+		p.newState = func() workloadState { return newJSONState() }
+		p.footprint = func(size int) int64 { return 16*int64(size) + 32<<10 }
+	case "gzip":
+		p.newState = func() workloadState { return &gzipState{} }
+		p.footprint = func(size int) int64 { return int64(size) + int64(size)/2 + gzipWriterBytes }
+	case "sha256":
+		p.newState = func() workloadState { return &shaState{h: sha256.New()} }
+		p.footprint = func(size int) int64 { return int64(size) + 1<<10 } // plus the hasher
+	case "varint":
+		// The encoded fields plus the decoded values, both grown by append.
+		p.newState = func() workloadState { return &varintState{} }
+		p.footprint = func(size int) int64 { return 14 * int64(size) }
+	default:
+		return nil, fmt.Errorf("unknown workload %q", kind)
+	}
+	p.states.New = func() any { return p.newState() }
+	return p, nil
+}
+
+func (p *WorkloadProcessor) variant() string {
+	if p.pooled {
+		return "pooled"
+	}
+	return "naive"
+}
+
+// Footprint estimates the bytes one call holds for size bytes of input.
+// The pooled variant reuses that memory across calls but still holds it
+// while a call runs, so both variants report the same figure.
+func (p *WorkloadProcessor) Footprint(size int) int64 {
+	return p.footprint(size)
+}
+
+func (p *WorkloadProcessor) Process(size int) (string, error) {
+	n, err := p.ProcessTo(context.Background(), io.Discard, size)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s (%s): Processed %d bytes into %d", p.kind, p.variant(), size, n), nil
+}
+
+func (p *WorkloadProcessor) ProcessTo(ctx context.Context, w io.Writer, size int) (int64, error) {
+	if !p.pooled {
+		return p.newState().run(ctx, w, size)
+	}
+	st := p.states.Get().(workloadState)
+	n, err := st.run(ctx, w, size)
+	// As with BufferPool, states that grew past the largest class are
+	// dropped so one huge request does not pin memory in the pool.
+	if st.retained() <= DefaultMaxClassSize {
+		p.states.Put(st)
+	}
+	return n, err
+}
+
+// workloadText is the repeating input for the byte-oriented workloads:
+// log-like text, so gzip sees realistic redundancy.
+const workloadText = `ts=2024-05-01T12:00:00Z level=info msg="request handled" method=GET path=/api/orders status=200 bytes=5123 dur_ms=12 user=u-48213 region=eu-west-1` + "\n"
+
+func fillText(buf []byte) {
+	for off := 0; off < len(buf); off += len(workloadText) {
+		copy(buf[off:], workloadText)
+	}
+}
+
+// grow returns buf resliced to n bytes, reallocating only when too small.
+func grow(buf []byte, n int) []byte {
+	if cap(buf) < n {
+		return make([]byte, n)
+	}
+	return buf[:n]
+}
+
+// writeInChunks feeds buf to w a streamChunk at a time, checking ctx in
+// between, for workloads whose cost scales with input size.
+func writeInChunks(ctx context.Context, w io.Writer, buf []byte) error {
+	for off := 0; off < len(buf); off += streamChunk {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if _, err := w.Write(buf[off:min(off+streamChunk, len(buf))]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Order is the request object the json workload encodes and decodes.
+type Order struct {
+	ID       int64       `json:"id"`
+	Customer string      `json:"customer"`
+	Tags     []string    `json:"tags"`
+	Items    []OrderItem `json:"items"`
+}
+
+type OrderItem struct {
+	SKU   string  `json:"sku"`
+	Qty   int     `json:"qty"`
+	Price float64 `json:"price"`
+}
+
+// orderItemBytes is roughly the encoded size of one OrderItem.
+const orderItemBytes = 42
+
+var skus = func() []string {
+	s := make([]string, 64)
+	for i := range s {
+		s[i] = fmt.Sprintf("SKU-%05d", i*7919%100000)
+	}
+	return s
+}()
+
+type jsonState struct {
+	order Order
+	back  Order
+	buf   bytes.Buffer
+	enc   *json.Encoder
+}
+
+func newJSONState() *jsonState {
+	st := &jsonState{}
+	st.enc = json.NewEncoder(&st.buf)
+	return st
+}
+
+// run builds an order whose encoding is about size bytes, encodes it and
+// decodes it back, the round trip of a typical JSON API call.
+func (st *jsonState) run(ctx context.Context, w io.Writer, size int) (int64, error) {
+	st.order.ID = int64(size)
+	st.order.Customer = "c-48213"
+	st.order.Tags = append(st.order.Tags[:0], "priority", "eu-west-1")
+	st.order.Items = st.order.Items[:0]
+	for i := 0; i < max(1, size/orderItemBytes); i++ {
+		st.order.Items = append(st.order.Items, OrderItem{SKU: skus[i%len(skus)], Qty: i%9 + 1, Price: float64(i%400) / 4})
+	}
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	st.buf.Reset()
+	if err := st.enc.Encode(&st.order); err != nil {
+		return 0, err
+	}
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	if err := json.Unmarshal(st.buf.Bytes(), &st.back); err != nil {
+		return 0, err
+	}
+	if len(st.back.Items) != len(st.order.Items) {
+		return 0, fmt.Errorf("json round trip lost items: %d != %d", len(st.back.Items), len(st.order.Items))
+	}
+	n, err := w.Write(st.buf.Bytes())
+	return int64(n), err
+}
+
+func (st *jsonState) retained() int {
+	return st.buf.Cap() + cap(st.order.Items)*orderItemBytes*2
+}
+
+type gzipState struct {
+	in  []byte
+	out bytes.Buffer
+	zw  *gzip.Writer
+}
+
+// run compresses size bytes of log text. gzip.NewWriter allocates several
+// hundred KB of compressor state, which is what makes the naive variant
+// expensive.
+func (st *gzipState) run(ctx context.Context, w io.Writer, size int) (int64, error) {
+	st.in = grow(st.in, size)
+	fillText(st.in)
+	st.out.Reset()
+	if st.zw == nil {
+		st.zw = gzip.NewWriter(&st.out)
+	} else {
+		st.zw.Reset(&st.out)
+	}
+	if err := writeInChunks(ctx, st.zw, st.in); err != nil {
+		return 0, err
+	}
+	if err := st.zw.Close(); err != nil {
+		return 0, err
+	}
+	n, err := w.Write(st.out.Bytes())
+	return int64(n), err
+}
+
+func (st *gzipState) retained() int {
+	return cap(st.in) + st.out.Cap()
+}
+
+type shaState struct {
+	in  []byte
+	h   hash.Hash
+	sum [sha256.Size]byte
+}
+
+// run hashes size bytes of log text and writes the digest.
+func (st *shaState) run(ctx context.Context, w io.Writer, size int) (int64, error) {
+	st.in = grow(st.in, size)
+	fillText(st.in)
+	st.h.Reset()
+	if err := writeInChunks(ctx, st.h, st.in); err != nil {
+		return 0, err
+	}
+	n, err := w.Write(st.h.Sum(st.sum[:0]))
+	return int64(n), err
+}
+
+func (st *shaState) retained() int {
+	return cap(st.in)
+}
+
+type varintState struct {
+	out  []byte
+	vals []uint64
+}
+
+// run encodes about size bytes of protobuf-style (tag, varint) fields and
+// decodes them back. The naive variant starts from nil slices and pays for
+// every append that grows them.
+func (st *varintState) run(ctx context.Context, w io.Writer, size int) (int64, error) {
+	st.out = st.out[:0]
+	fields := 0
+	for i := uint64(0); len(st.out) < size; i++ {
+		if i%4096 == 0 {
+			if err := ctx.Err(); err != nil {
+				return 0, err
+			}
+		}
+		st.out = binary.AppendUvarint(st.out, (i%15+1)<<3) // field number, wire type 0
+		st.out = binary.AppendUvarint(st.out, i*2654435761>>(i%5*8))
+		fields++
+	}
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	st.vals = st.vals[:0]
+	for buf := st.out; len(buf) > 0; {
+		tag, n := binary.Uvarint(buf)
+		if n <= 0 {
+			return 0, fmt.Errorf("bad tag varint")
+		}
+		v, m := binary.Uvarint(buf[n:])
+		if m <= 0 || tag&7 != 0 {
+			return 0, fmt.Errorf("bad field %d", tag>>3)
+		}
+		st.vals = append(st.vals, v)
+		buf = buf[n+m:]
+	}
+	if len(st.vals) != fields {
+		return 0, fmt.Errorf("varint round trip decoded %d of %d fields", len(st.vals), fields)
+	}
+	n, err := w.Write(st.out)
+	return int64(n), err
+}
+
+func (st *varintState) retained() int {
+	return cap(st.out) + cap(st.vals)*8
+}
@@ -0,0 +1,129 @@
+package processor
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"io"
+	"runtime"
+	"testing"
+)
+
+func workloadProcessor(t testing.TB, kind string, pooled bool) *WorkloadProcessor {
+	t.Helper()
+	p, err := NewWorkloadProcessor(kind, pooled)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestWorkloadOutputs(t *testing.T) {
+	const size = 20000
+	text := make([]byte, size)
+	fillText(text)
+	wantSum := sha256.Sum256(text)
+
+	for _, pooled := range []bool{false, true} {
+		// Run twice so the pooled variant also reuses a dirty state.
+		for run := 0; run < 2; run++ {
+			var out bytes.Buffer
+			if _, err := workloadProcessor(t, "json", pooled).ProcessTo(context.Background(), &out, size); err != nil {
+				t.Fatal(err)
+			}
+			var order Order
+			if err := json.Unmarshal(out.Bytes(), &order); err != nil || len(order.Items) != size/orderItemBytes {
+				t.Errorf("json: %d items, err %v", len(order.Items), err)
+			}
+			if out.Len() < size/2 || out.Len() > size*2 {
+				t.Errorf("json: encoded %d bytes for size %d", out.Len(), size)
+			}
+
+			out.Reset()
+			if _, err := workloadProcessor(t, "gzip", pooled).ProcessTo(context.Background(), &out, size); err != nil {
+				t.Fatal(err)
+			}
+			zr, err := gzip.NewReader(&out)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if plain, err := io.ReadAll(zr); err != nil || !bytes.Equal(plain, text) {
+				t.Errorf("gzip: round trip mismatch (%d bytes, err %v)", len(plain), err)
+			}
+
+			out.Reset()
+			if _, err := workloadProcessor(t, "sha256", pooled).ProcessTo(context.Background(), &out, size); err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(out.Bytes(), wantSum[:]) {
+				t.Errorf("sha256: digest %x, want %x", out.Bytes(), wantSum)
+			}
+
+			out.Reset()
+			if n, err := workloadProcessor(t, "varint", pooled).ProcessTo(context.Background(), &out, size); err != nil || n < size || n > size+20 {
+				t.Errorf("varint: wrote %d bytes for size %d, err %v", n, size, err)
+			}
+		}
+	}
+}
+
+func TestWorkloadPooledAllocatesLess(t *testing.T) {
+	if raceEnabled {
+		t.Skip("sync.Pool drops items under the race detector")
+	}
+	ctx := context.Background()
+	for _, kind := range WorkloadKinds {
+		var bytesPerRun [2]float64
+		for i, pooled := range []bool{false, true} {
+			p := workloadProcessor(t, kind, pooled)
+			p.ProcessTo(ctx, io.Discard, 16384) // warm the pool
+			var before, after runtime.MemStats
+			runtime.ReadMemStats(&before)
+			for n := 0; n < 50; n++ {
+				p.ProcessTo(ctx, io.Discard, 16384)
+			}
+			runtime.ReadMemStats(&after)
+			bytesPerRun[i] = float64(after.TotalAlloc-before.TotalAlloc) / 50
+		}
+		if bytesPerRun[1] >= bytesPerRun[0]/2 {
+			t.Errorf("%s: pooled allocates %.0f B/op, naive %.0f B/op", kind, bytesPerRun[1], bytesPerRun[0])
+		}
+	}
+}
+
+// TestWorkloadFootprintCoversAllocations checks that admission control
+// reserves at least what a naive call allocates, the worst case.
+func TestWorkloadFootprintCoversAllocations(t *testing.T) {
+	ctx := context.Background()
+	for _, kind := range WorkloadKinds {
+		for _, size := range []int{1 << 10, 64 << 10, 1 << 20} {
+			p := workloadProcessor(t, kind, false)
+			var before, after runtime.MemStats
+			runtime.ReadMemStats(&before)
+			p.ProcessTo(ctx, io.Discard, size)
+			runtime.ReadMemStats(&after)
+			if got, want := Footprint(p, size), int64(after.TotalAlloc-before.TotalAlloc); got < want {
+				t.Errorf("%s: footprint %d for size %d, but one call allocated %d", kind, got, size, want)
+			}
+		}
+	}
+	if got := Footprint(NewNaiveProcessor(), 4096); got != 4096 {
+		t.Errorf("naive footprint = %d, want the size", got)
+	}
+}
+
+func TestWorkloadCancellationAndUnknownKind(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	for _, kind := range WorkloadKinds {
+		if _, err := workloadProcessor(t, kind, true).ProcessTo(ctx, io.Discard, 100000); !errors.Is(err, context.Canceled) {
+			t.Errorf("%s ignored cancellation: %v", kind, err)
+		}
+	}
+	if _, err := NewWorkloadProcessor("xml", false); err == nil {
+		t.Error("unknown workload kind accepted")
+	}
+}
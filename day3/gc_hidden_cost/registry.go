@@ -0,0 +1,221 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"gc_hidden_cost/processor"
+	"lessonkit/dashboard"
+)
+
+// registeredProcessor is one processor served at /process/{name}, with the
+// stats the dashboard and /api/stats show for it.
+type registeredProcessor struct {
+	name  string
+	label string
+	// new builds a fresh instance; experiments use one per run so a pool
+	// warmed by one run cannot help the next.
+	new     func() processor.Processor
+	proc    processor.Processor
+	latency *latencyTracker
+
+	mu     sync.Mutex
+	lastGC gcSettings // in effect for the last request
+}
+
+// processorRegistry keeps processors in registration order, which is the
+// order the dashboard lists them in.
+type processorRegistry struct {
+	byName map[string]*registeredProcessor
+	all    []*registeredProcessor
+}
+
+// registry holds every processor the app serves. naive and pooled come
+// first; strategies.go registers the rest.
+var registry = newProcessorRegistry()
+
+func newProcessorRegistry() *processorRegistry {
+	return &processorRegistry{byName: map[string]*registeredProcessor{}}
+}
+
+func init() {
+	registry.Register("naive", "Naive", func() processor.Processor { return processor.NewNaiveProcessor() })
+	registry.Register("pooled", "Pooled", func() processor.Processor { return processor.NewPooledProcessor() })
+	registerStrategies(registry)
+}
+
+// Register adds a processor under name, building the instance that serves
+// requests. It panics on a duplicate name, which is a programming error.
+func (reg *processorRegistry) Register(name, label string, new func() processor.Processor) *registeredProcessor {
+	if _, dup := reg.byName[name]; dup {
+		panic(fmt.Sprintf("processor %q registered twice", name))
+	}
+	p := &registeredProcessor{name: name, label: label, new: new, proc: new(), latency: newLatencyTracker()}
+	reg.byName[name] = p
+	reg.all = append(reg.all, p)
+	return p
+}
+
+func (reg *processorRegistry) Lookup(name string) (*registeredProcessor, bool) {
+	p, ok := reg.byName[name]
+	return p, ok
+}
+
+func (reg *processorRegistry) All() []*registeredProcessor {
+	return reg.all
+}
+
+// Routes serves every processor at /process/{name}, and at /<name> as
+// well, the paths the lesson's hey runs and scripts use.
+func (reg *processorRegistry) Routes(mux *http.ServeMux) {
+	mux.HandleFunc("/process/{name}", func(w http.ResponseWriter, r *http.Request) {
+		p, ok := reg.Lookup(r.PathValue("name"))
+		if !ok {
+			http.Error(w, fmt.Sprintf("unknown processor %q", r.PathValue("name")), http.StatusNotFound)
+			return
+		}
+		p.ServeHTTP(w, r)
+	})
+	for _, p := range reg.all {
+		mux.Handle("/"+p.name, p)
+	}
+}
+
+func (p *registeredProcessor) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	handleProcessWithStats(p.proc, p.record)(w, r)
+}
+
+func (p *registeredProcessor) record(d time.Duration) {
+	p.latency.Record(d)
+	p.mu.Lock()
+	p.lastGC = activeGCSettings()
+	p.mu.Unlock()
+	requestMetrics.Observe(p.name, d)
+}
+
+func (p *registeredProcessor) lastGCSettings() gcSettings {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.lastGC
+}
+
+// reuse returns the processor's own counters: pool stats for free lists,
+// slab stats for the arena, and the heap fallback count for the stack array.
+func (p *registeredProcessor) reuse() any {
+	switch proc := p.proc.(type) {
+	case processor.PoolReporter:
+		return proc.Stats()
+	case *processor.ArenaProcessor:
+		return proc.Stats()
+	case *processor.StackProcessor:
+		return map[string]uint64{"heap_fallbacks": proc.Fallbacks()}
+	}
+	return nil
+}
+
+// reuseSummary is the one-line form of reuse for the dashboard.
+func (p *registeredProcessor) reuseSummary() string {
+	switch st := p.reuse().(type) {
+	case processor.PoolStats:
+		return fmt.Sprintf("%.1f%% reuse, %d discards", 100*st.ReuseRate(), st.Discards)
+	case processor.ArenaStats:
+		return fmt.Sprintf("%d resets, %d slabs, %d oversize", st.Resets, st.SlabsMade, st.Oversize)
+	case map[string]uint64:
+		return fmt.Sprintf("%d heap fallbacks", st["heap_fallbacks"])
+	}
+	return "—"
+}
+
+type processorStatsJSON struct {
+	Label        string         `json:"label"`
+	RequestCount uint64         `json:"request_count"`
+	Latency      latencySummary `json:"latency"`
+	LastGC       gcSettings     `json:"last_gc_settings"`
+	Reuse        any            `json:"reuse,omitempty"`
+}
+
+func (reg *processorRegistry) statsJSON() map[string]processorStatsJSON {
+	out := map[string]processorStatsJSON{}
+	for _, p := range reg.all {
+		sum := p.latency.Summary()
+		out[p.name] = processorStatsJSON{Label: p.label, RequestCount: sum.Total.Count, Latency: sum,
+			LastGC: p.lastGCSettings(), Reuse: p.reuse()}
+	}
+	return out
+}
+
+// handleStatsJSON reports every processor under "processors". The flat
+// naive_* and pooled_* keys, pooled_buffer_pool and strategies are the
+// shape /api/stats had before the registry, kept for existing scripts.
+func handleStatsJSON(w http.ResponseWriter, r *http.Request) {
+	procs := registry.statsJSON()
+	out := map[string]any{
+		"gc_settings": activeGCSettings(),
+		"processors":  procs,
+	}
+	strategies := map[string]any{}
+	for name, st := range procs {
+		if !slices.Contains(headlineProcessors, name) {
+			strategies[name] = map[string]any{"request_count": st.RequestCount, "latency": st.Latency, "reuse": st.Reuse}
+			continue
+		}
+		out[name+"_request_count"] = st.RequestCount
+		out[name+"_latency"] = st.Latency
+		out["last_"+name+"_gc_settings"] = st.LastGC
+	}
+	out["strategies"] = strategies
+	if st, ok := procs["pooled"]; ok {
+		out["pooled_buffer_pool"] = st.Reuse
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(out)
+}
+
+// handlerStats feeds per-handler counters to the /events stream, reporting
+// the last minute's p99 so one outlier does not define the live table.
+func handlerStats() map[string]dashboard.HandlerStats {
+	stats := map[string]dashboard.HandlerStats{}
+	for _, p := range registry.All() {
+		sum := p.latency.Summary()
+		stats[p.name] = dashboard.HandlerStats{Requests: sum.Total.Count, P99Ns: sum.Recent.P99Ns}
+	}
+	return stats
+}
+
+// headlineProcessors get live cards above the processor table: they are
+// the comparison the lesson is about.
+var headlineProcessors = []string{"naive", "pooled"}
+
+func processorPanel() []dashboard.Block {
+	var cards dashboard.Cards
+	for _, name := range headlineProcessors {
+		if p, ok := registry.Lookup(name); ok {
+			sum := p.latency.Summary()
+			cards = append(cards,
+				dashboard.Card{Label: p.label + " requests", Value: sum.Total.Count, Live: true},
+				dashboard.Card{Label: p.label + " p99 (last minute)", Value: durationOrDash(sum.Recent.P99Ns), Live: true})
+		}
+	}
+	table := &dashboard.Table{Columns: []string{"Processor", "Requests", "Last minute p50 / p95 / p99", "All time p50 / p95 / p99", "Reuse", "Last GC settings"}}
+	for _, p := range registry.All() {
+		sum := p.latency.Summary()
+		gc := "—"
+		if sum.Total.Count > 0 {
+			gc = p.lastGCSettings().String()
+		}
+		table.Rows = append(table.Rows, []any{p.label, sum.Total.Count, latencyCell(sum.Recent), latencyCell(sum.Total), p.reuseSummary(), gc})
+	}
+	return []dashboard.Block{cards, table}
+}
+
+func processorActions() dashboard.Links {
+	var links dashboard.Links
+	for _, p := range registry.All() {
+		links = append(links, dashboard.Link{Label: fmt.Sprintf("Run %s (4KB)", p.label), URL: "/process/" + p.name + "?size=4096"})
+	}
+	return links
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"gc_hidden_cost/processor"
+)
+
+// registerStrategies adds the processors beyond naive and pooled: the other
+// buffer strategies, then the naive and pooled variant of each realistic
+// workload.
+func registerStrategies(reg *processorRegistry) {
+	reg.Register("chan", "Chan free-list", func() processor.Processor { return processor.NewChanProcessor(processor.DefaultFreeListSize) })
+	reg.Register("sharded", "Sharded", func() processor.Processor { return processor.NewShardedProcessor(0) })
+	reg.Register("arena", "Arena", func() processor.Processor {
+		return processor.NewArenaProcessor(processor.DefaultSlabSize, processor.DefaultArenaBatch)
+	})
+	reg.Register("stack", "Stack array", func() processor.Processor { return processor.NewStackProcessor() })
+	registerWorkloads(reg)
+}
+
+// registerWorkloads adds "<kind>-naive" and "<kind>-pooled" for each
+// processor.WorkloadKinds entry.
+func registerWorkloads(reg *processorRegistry) {
+	for _, kind := range processor.WorkloadKinds {
+		for _, pooled := range []bool{false, true} {
+			variant := map[bool]string{false: "naive", true: "pooled"}[pooled]
+			reg.Register(kind+"-"+variant, fmt.Sprintf("%s %s", strings.ToUpper(kind), variant), func() processor.Processor {
+				p, err := processor.NewWorkloadProcessor(kind, pooled)
+				if err != nil {
+					panic(err) // kind comes from WorkloadKinds
+				}
+				return p
+			})
+		}
+	}
+}
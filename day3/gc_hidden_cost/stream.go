@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"gc_hidden_cost/processor"
+	"lessonkit/tracing"
+)
+
+// maxStreamTimeout caps the ?timeout= a streaming request may ask for.
+const maxStreamTimeout = time.Minute
+
+// streamProcess sends the processed bytes themselves as the response body
+// (?stream=1). The request context reaches the processor, so a client that
+// disconnects, or a ?timeout= that expires, stops the work at the next
+// chunk instead of after all size bytes.
+func streamProcess(w http.ResponseWriter, r *http.Request, p processor.Processor, size int, recordStats func(time.Duration)) {
+	ctx := r.Context()
+	if s := r.URL.Query().Get("timeout"); s != "" {
+		timeout, err := time.ParseDuration(s)
+		if err != nil || timeout <= 0 || timeout > maxStreamTimeout {
+			http.Error(w, fmt.Sprintf("invalid 'timeout' parameter (up to %s)", maxStreamTimeout), http.StatusBadRequest)
+			return
+		}
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	sp, native := p.(processor.StreamProcessor)
+	if !native {
+		sp = processor.Adapt(p)
+	}
+	w.Header().Set("Content-Type", "application/octet-stream")
+	if native {
+		// A declared length lets clients detect a body cut short by
+		// cancellation; the adapter writes a message of unknown length.
+		w.Header().Set("Content-Length", strconv.Itoa(size))
+	}
+
+	ctx, span := tracing.Start(ctx, "process.stream")
+	defer span.End()
+	span.SetAttr("size", strconv.Itoa(size))
+	start := time.Now()
+	n, err := sp.ProcessTo(ctx, w, size)
+	duration := time.Since(start)
+	if err != nil {
+		span.SetError(err.Error())
+		slog.InfoContext(ctx, "stream stopped early", "written", n, "size", size, "err", err)
+		if n == 0 && r.Context().Err() == nil {
+			w.Header().Del("Content-Length")
+			status := http.StatusInternalServerError
+			if ctx.Err() != nil {
+				status = http.StatusGatewayTimeout
+			}
+			http.Error(w, fmt.Sprintf("processing error: %v", err), status)
+		}
+		return
+	}
+	if recordStats != nil {
+		recordStats(duration)
+	}
+}
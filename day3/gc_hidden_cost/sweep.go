@@ -0,0 +1,330 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"runtime"
+	rtmetrics "runtime/metrics"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"gc_hidden_cost/processor"
+	"lessonkit/dashboard"
+)
+
+// Sweep limits keep a single request from monopolizing the server.
+const (
+	maxSweepValues      = 8
+	maxSweepConcurrency = 64
+	maxSweepSize        = 16 << 20
+	minSweepDuration    = 100 * time.Millisecond
+	maxSweepDuration    = 5 * time.Second
+	maxSweepTotal       = 60 * time.Second
+	heapSampleInterval  = 10 * time.Millisecond
+)
+
+// loadResult is the outcome of driving one processor for a fixed duration.
+type loadResult struct {
+	Processor       string  `json:"processor"`
+	GOGC            string  `json:"gogc"`
+	Ops             uint64  `json:"ops"`
+	OpsPerSec       float64 `json:"ops_per_sec"`
+	Errors          uint64  `json:"errors"`
+	ElapsedNs       int64   `json:"elapsed_ns"`
+	P50Ns           int64   `json:"p50_ns"`
+	P90Ns           int64   `json:"p90_ns"`
+	P99Ns           int64   `json:"p99_ns"`
+	MaxNs           int64   `json:"max_ns"`
+	NumGC           uint32  `json:"num_gc"`
+	PauseTotalNs    uint64  `json:"pause_total_ns"`
+	TotalAllocBytes uint64  `json:"total_alloc_bytes"`
+	AllocBytesPerS  float64 `json:"alloc_bytes_per_sec"`
+	MallocsPerOp    float64 `json:"mallocs_per_op"`
+	PeakHeapBytes   uint64  `json:"peak_heap_bytes"`
+	AvgHeapBytes    uint64  `json:"avg_heap_bytes"`
+	// Pool is set for processors that implement processor.PoolReporter.
+	Pool *processor.PoolStats `json:"pool,omitempty"`
+	// Call is the method each op timed: "ProcessTo" into io.Discard, or
+	// "Process" for processors without ProcessTo. It is empty in results
+	// stored before it was recorded, which all timed Process.
+	Call string `json:"call,omitempty"`
+}
+
+// callMode is r.Call, reading an empty Call as "Process".
+func (r loadResult) callMode() string {
+	if r.Call == "" {
+		return "Process"
+	}
+	return r.Call
+}
+
+// runProcessorLoad drives p from concurrency goroutines for d. It times
+// every call and samples live heap objects every heapSampleInterval via
+// runtime/metrics (which, unlike ReadMemStats, does not stop the world).
+// Each call is ProcessTo into io.Discard, so the load measures the buffer
+// strategy without a formatted result per call; processors without
+// ProcessTo fall back to Process through processor.Adapt. The result's
+// Call field records which one ran.
+func runProcessorLoad(name string, p processor.Processor, size, concurrency int, d time.Duration) loadResult {
+	runtime.GC()
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+
+	var ops, errs atomic.Uint64
+	sp := processor.Adapt(p)
+	ctx, stop := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	hists := make([]latencyHistogram, concurrency)
+	for i := range hists {
+		wg.Add(1)
+		go func(h *latencyHistogram) {
+			defer wg.Done()
+			for ctx.Err() == nil {
+				start := time.Now()
+				_, err := sp.ProcessTo(ctx, io.Discard, size)
+				if ctx.Err() != nil {
+					return // cut short by the end of the run; not an op
+				}
+				h.Record(time.Since(start))
+				if err != nil {
+					errs.Add(1)
+				}
+				ops.Add(1)
+			}
+		}(&hists[i])
+	}
+
+	sample := []rtmetrics.Sample{{Name: "/memory/classes/heap/objects:bytes"}}
+	var peak, sum, n uint64
+	start := time.Now()
+	tick := time.NewTicker(heapSampleInterval)
+	for time.Since(start) < d {
+		<-tick.C
+		rtmetrics.Read(sample)
+		v := sample[0].Value.Uint64()
+		sum += v
+		n++
+		if v > peak {
+			peak = v
+		}
+	}
+	tick.Stop()
+	stop()
+	wg.Wait()
+	elapsed := time.Since(start)
+	runtime.ReadMemStats(&after)
+
+	var lat latencyHistogram
+	for i := range hists {
+		lat.Merge(&hists[i])
+	}
+
+	res := loadResult{
+		Processor:       name,
+		GOGC:            formatGOGC(activeGCSettings().GOGC),
+		Ops:             ops.Load(),
+		Errors:          errs.Load(),
+		ElapsedNs:       elapsed.Nanoseconds(),
+		P50Ns:           lat.Quantile(0.50).Nanoseconds(),
+		P90Ns:           lat.Quantile(0.90).Nanoseconds(),
+		P99Ns:           lat.Quantile(0.99).Nanoseconds(),
+		MaxNs:           lat.Max().Nanoseconds(),
+		NumGC:           after.NumGC - before.NumGC,
+		PauseTotalNs:    after.PauseTotalNs - before.PauseTotalNs,
+		TotalAllocBytes: after.TotalAlloc - before.TotalAlloc,
+		PeakHeapBytes:   peak,
+		Call:            "Process",
+	}
+	if _, ok := p.(processor.StreamProcessor); ok {
+		res.Call = "ProcessTo"
+	}
+	res.OpsPerSec = float64(res.Ops) / elapsed.Seconds()
+	res.AllocBytesPerS = float64(res.TotalAllocBytes) / elapsed.Seconds()
+	if res.Ops > 0 {
+		res.MallocsPerOp = float64(after.Mallocs-before.Mallocs) / float64(res.Ops)
+	}
+	if n > 0 {
+		res.AvgHeapBytes = sum / n
+	}
+	if pr, ok := p.(processor.PoolReporter); ok {
+		st := pr.Stats()
+		res.Pool = &st
+	}
+	return res
+}
+
+type sweepJSON struct {
+	Size        int          `json:"size"`
+	Concurrency int          `json:"concurrency"`
+	DurationNs  int64        `json:"duration_ns"`
+	Restored    gcSettings   `json:"restored"`
+	Results     []loadResult `json:"results"`
+}
+
+// handleGOGCSweep (POST) runs naive and pooled load at each GOGC in values
+// and reports throughput against heap size. Form values: values (comma
+// list, default 25,50,100,200,400), size, concurrency, duration (per run),
+// format=json. The previous GC settings are restored afterwards. It is
+// POST-only because it changes process-wide settings and burns CPU.
+func handleGOGCSweep(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", "POST")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	q := r.Form
+	values := []int{25, 50, 100, 200, 400}
+	if s := q.Get("values"); s != "" {
+		values = values[:0]
+		for _, v := range strings.Split(s, ",") {
+			pct, err := parseGOGC(strings.TrimSpace(v))
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			if err := checkGCSettings(gcSettings{GOGC: pct, MemoryLimit: activeGCSettings().MemoryLimit}); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			values = append(values, pct)
+		}
+	}
+	if len(values) == 0 || len(values) > maxSweepValues {
+		http.Error(w, fmt.Sprintf("between 1 and %d GOGC values required", maxSweepValues), http.StatusBadRequest)
+		return
+	}
+	size, concurrency, duration, err := parseLoadParams(q, time.Second)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if total := time.Duration(2*len(values)) * duration; total > maxSweepTotal {
+		http.Error(w, fmt.Sprintf("sweep would take %s (max %s)", total, maxSweepTotal), http.StatusBadRequest)
+		return
+	}
+	if !loadRunning.CompareAndSwap(false, true) {
+		http.Error(w, "a load experiment is already running", http.StatusConflict)
+		return
+	}
+	defer loadRunning.Store(false)
+	release, ok := admit.admitLoad(w, r, concurrency,
+		loadFootprint(size, processor.NewNaiveProcessor(), processor.NewPooledProcessor()))
+	if !ok {
+		return
+	}
+	defer release()
+
+	original := activeGCSettings()
+	out := sweepJSON{Size: size, Concurrency: concurrency, DurationNs: duration.Nanoseconds(), Restored: original}
+	for _, pct := range values {
+		applyGCSettings(gcSettings{GOGC: pct, MemoryLimit: original.MemoryLimit})
+		out.Results = append(out.Results,
+			runProcessorLoad("naive", processor.NewNaiveProcessor(), size, concurrency, duration),
+			runProcessorLoad("pooled", processor.NewPooledProcessor(), size, concurrency, duration))
+	}
+	applyGCSettings(original)
+
+	if q.Get("format") == "json" {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(out)
+		return
+	}
+	sweepPage(out).ServeHTTP(w, r)
+}
+
+// sweepPage renders a sweep as a one-off dashboard page: throughput and
+// peak heap per processor across the GOGC values, plus the raw table.
+func sweepPage(out sweepJSON) *dashboard.Dashboard {
+	d := dashboard.New("GOGC Sweep", dashboard.Light)
+	d.StreamPath = ""
+	d.Intro = fmt.Sprintf("Each run drives the processor with %d goroutines at %d bytes per call for %s, streaming each result into io.Discard. Higher GOGC trades memory for fewer collections; pooling should flatten both curves.",
+		out.Concurrency, out.Size, time.Duration(out.DurationNs))
+	d.Nav = []dashboard.Link{{Label: "Dashboard", URL: "/"}}
+
+	byProc := map[string][]loadResult{}
+	var procs []string
+	for _, res := range out.Results {
+		if _, ok := byProc[res.Processor]; !ok {
+			procs = append(procs, res.Processor)
+		}
+		byProc[res.Processor] = append(byProc[res.Processor], res)
+	}
+	charts := &dashboard.Table{Columns: []string{"Processor", "Ops/s by GOGC", "Peak heap by GOGC", "GOGC values"}}
+	for _, name := range procs {
+		var ops, heap []float64
+		var labels []string
+		for _, res := range byProc[name] {
+			ops = append(ops, res.OpsPerSec)
+			heap = append(heap, float64(res.PeakHeapBytes))
+			labels = append(labels, res.GOGC)
+		}
+		charts.Rows = append(charts.Rows, []any{name,
+			dashboard.Chart{Values: ops, Width: 240, Height: 48, Color: "#198754"},
+			dashboard.Chart{Values: heap, Width: 240, Height: 48, Color: "#dc3545"},
+			strings.Join(labels, " → ")})
+	}
+
+	table := &dashboard.Table{Columns: []string{"GOGC", "Processor", "Ops/s", "p99", "GC cycles", "Pause total", "Allocated", "Peak heap", "Avg heap"}}
+	for _, res := range out.Results {
+		table.Rows = append(table.Rows, []any{res.GOGC, res.Processor, fmt.Sprintf("%.0f", res.OpsPerSec), time.Duration(res.P99Ns),
+			res.NumGC, time.Duration(res.PauseTotalNs), mib(res.TotalAllocBytes), mib(res.PeakHeapBytes), mib(res.AvgHeapBytes)})
+	}
+	d.Add(dashboard.Panel{ID: "charts", Title: "Throughput vs memory", Render: func() []dashboard.Block { return []dashboard.Block{charts} }})
+	d.Add(dashboard.Panel{ID: "results", Title: "Runs", Render: func() []dashboard.Block {
+		return []dashboard.Block{table, dashboard.Text("GC settings restored to " + out.Restored.String() + ".")}
+	}})
+	return d
+}
+
+// parseLoadParams reads the size, concurrency and duration parameters shared
+// by the sweep and experiment runners, applying the sweep limits.
+func parseLoadParams(q url.Values, defDuration time.Duration) (size, concurrency int, duration time.Duration, err error) {
+	size, err = intQuery(q.Get("size"), 65536)
+	if err != nil || size <= 0 || size > maxSweepSize {
+		return 0, 0, 0, fmt.Errorf("invalid 'size' parameter (1-%d)", maxSweepSize)
+	}
+	concurrency, err = intQuery(q.Get("concurrency"), runtime.GOMAXPROCS(0))
+	if err != nil || concurrency <= 0 || concurrency > maxSweepConcurrency {
+		return 0, 0, 0, fmt.Errorf("invalid 'concurrency' parameter (1-%d)", maxSweepConcurrency)
+	}
+	duration = defDuration
+	if s := q.Get("duration"); s != "" {
+		duration, err = time.ParseDuration(s)
+		if err != nil || duration < minSweepDuration || duration > maxSweepDuration {
+			return 0, 0, 0, fmt.Errorf("invalid 'duration' parameter (%s-%s)", minSweepDuration, maxSweepDuration)
+		}
+	}
+	return size, concurrency, duration, nil
+}
+
+// loadFootprint is the most any of procs holds in one call for size, what
+// each load worker reserves from admission control.
+func loadFootprint(size int, procs ...processor.Processor) int64 {
+	var n int64
+	for _, p := range procs {
+		n = max(n, processor.Footprint(p, size))
+	}
+	return n
+}
+
+func intQuery(s string, def int) (int, error) {
+	if s == "" {
+		return def, nil
+	}
+	return strconv.Atoi(s)
+}
+
+func mib(b uint64) string {
+	return fmt.Sprintf("%.1f MiB", float64(b)/(1<<20))
+}
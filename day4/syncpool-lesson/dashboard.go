@@ -0,0 +1,80 @@
+package main
+
+import (
+	"net/http"
+	"runtime"
+	"time"
+
+	"lessonkit/dashboard"
+)
+
+var dash = newDashboard()
+
+func newDashboard() *dashboard.Dashboard {
+	d := dashboard.New("sync.Pool Lesson Dashboard", dashboard.Light)
+	d.Intro = "Buggy (no buffer reset) vs Fixed (reset before Put). Use actions below to generate load; the dashboard updates live."
+	d.Nav = []dashboard.Link{
+		{Label: "Dashboard", URL: "/"},
+		{Label: "JSON MemStats", URL: "/debug/mem"},
+		{Label: "JSON Live Stats", URL: "/api/stats"},
+		{Label: "Prometheus", URL: "/metrics"},
+		{Label: "Event Stream", URL: "/events"},
+	}
+	d.EventsPath = "/events"
+	d.Add(dashboard.Panel{ID: "requests", Title: "Live request metrics", Render: requestPanel})
+	d.Add(dashboard.Panel{ID: "actions", Static: true, Render: func() []dashboard.Block {
+		return []dashboard.Block{dashboard.Links{
+			{Label: "Run Buggy (GET)", URL: "/buggy", NewTab: true},
+			{Label: "Run Fixed (GET)", URL: "/fixed", NewTab: true},
+		}}
+	}})
+	d.Add(dashboard.Panel{ID: "gc", Title: "GC & memory metrics", Render: memStatsPanel})
+	return d
+}
+
+func dashboardHandler(w http.ResponseWriter, r *http.Request) {
+	dash.ServeHTTP(w, r)
+}
+
+// handlerStats feeds per-handler counters to the /events stream.
+func handlerStats() map[string]dashboard.HandlerStats {
+	statsMu.RLock()
+	defer statsMu.RUnlock()
+	return map[string]dashboard.HandlerStats{
+		"buggy": {Requests: buggyRequestCount, LastNs: lastBuggyDurationNs},
+		"fixed": {Requests: fixedRequestCount, LastNs: lastFixedDurationNs},
+	}
+}
+
+func requestPanel() []dashboard.Block {
+	statsMu.RLock()
+	buggyCnt, fixedCnt := buggyRequestCount, fixedRequestCount
+	lastBuggyNs, lastFixedNs := lastBuggyDurationNs, lastFixedDurationNs
+	statsMu.RUnlock()
+	return []dashboard.Block{dashboard.Cards{
+		{Label: "Buggy requests", Value: buggyCnt, Live: true},
+		{Label: "Buggy last duration", Value: durationOrDash(lastBuggyNs), Live: true},
+		{Label: "Fixed requests", Value: fixedCnt, Live: true},
+		{Label: "Fixed last duration", Value: durationOrDash(lastFixedNs), Live: true},
+	}}
+}
+
+func memStatsPanel() []dashboard.Block {
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+	return []dashboard.Block{dashboard.Cards{
+		{Label: "Alloc", Value: m.Alloc},
+		{Label: "TotalAlloc", Value: m.TotalAlloc},
+		{Label: "NumGC", Value: m.NumGC},
+		{Label: "PauseTotalNs", Value: m.PauseTotalNs},
+		{Label: "HeapObjects", Value: m.HeapObjects},
+		{Label: "LastGC", Value: time.Unix(0, int64(m.LastGC)).Format(time.RFC3339Nano)},
+	}}
+}
+
+func durationOrDash(ns int64) string {
+	if ns <= 0 {
+		return "—"
+	}
+	return time.Duration(ns).String()
+}
@@ -1,3 +1,7 @@
 module syncpool-lesson
 
 go 1.22.0
+
+require lessonkit v0.0.0
+
+replace lessonkit => ../../lessonkit
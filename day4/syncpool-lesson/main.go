@@ -5,11 +5,16 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
-	"log"
+	"log/slog"
 	"net/http"
+	"os"
 	"runtime"
 	"sync"
 	"time"
+
+	"lessonkit/logging"
+	"lessonkit/metrics"
+	"lessonkit/tracing"
 )
 
 var bufferPool = sync.Pool{
@@ -27,17 +32,42 @@ var (
 	lastFixedDurationNs    int64
 )
 
+// Prometheus metrics served at /metrics
+var (
+	promRegistry   = metrics.NewRegistry()
+	requestMetrics = metrics.NewRequestMetrics(promRegistry)
+)
+
+func init() {
+	metrics.RegisterRuntimeMetrics(promRegistry)
+}
+
 func main() {
 	mux := http.NewServeMux()
 	mux.HandleFunc("/", dashboardHandler)
 	mux.HandleFunc("/dashboard", dashboardHandler)
+	mux.Handle(dash.StreamPath, dash.Stream())
+	mux.Handle(dash.EventsPath, dash.Events(handlerStats))
 	mux.HandleFunc("/buggy", buggyHandlerWithStats)
 	mux.HandleFunc("/fixed", fixedHandlerWithStats)
 	mux.HandleFunc("/debug/mem", handleMemStats)
 	mux.HandleFunc("/api/stats", handleStatsJSON)
+	mux.Handle("/metrics", promRegistry.Handler())
 	port := ":8080"
-	log.Printf("Server starting on port %s", port)
-	log.Fatal(http.ListenAndServe(port, mux))
+
+	logger, closeLog := logging.New(os.Stderr, logging.OptionsFromEnv())
+	slog.SetDefault(logger)
+	tracer, err := tracing.FromEnv("syncpool-lesson")
+	if err != nil {
+		logger.Error("tracing disabled", "err", err)
+		tracer = tracing.NewTracer("syncpool-lesson", nil)
+	}
+	logger.Info("server starting", "addr", port)
+	err = http.ListenAndServe(port, tracing.Middleware(tracer, logging.Middleware(logger, mux)))
+	logger.Error("server stopped", "err", err)
+	tracer.Close()
+	closeLog()
+	os.Exit(1)
 }
 
 func recordBuggyStats(d time.Duration) {
@@ -45,6 +75,7 @@ func recordBuggyStats(d time.Duration) {
 	buggyRequestCount++
 	lastBuggyDurationNs = d.Nanoseconds()
 	statsMu.Unlock()
+	requestMetrics.Observe("buggy", d)
 }
 
 func recordFixedStats(d time.Duration) {
@@ -52,6 +83,7 @@ func recordFixedStats(d time.Duration) {
 	fixedRequestCount++
 	lastFixedDurationNs = d.Nanoseconds()
 	statsMu.Unlock()
+	requestMetrics.Observe("fixed", d)
 }
 
 func handleStatsJSON(w http.ResponseWriter, r *http.Request) {
@@ -82,66 +114,6 @@ func handleMemStats(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
-func dashboardHandler(w http.ResponseWriter, r *http.Request) {
-	var m runtime.MemStats
-	runtime.ReadMemStats(&m)
-	lastGCStr := time.Unix(0, int64(m.LastGC)).Format(time.RFC3339Nano)
-	statsMu.RLock()
-	buggyCnt := buggyRequestCount
-	fixedCnt := fixedRequestCount
-	lastBuggyNs := lastBuggyDurationNs
-	lastFixedNs := lastFixedDurationNs
-	statsMu.RUnlock()
-	lastBuggyStr := "—"
-	if lastBuggyNs > 0 {
-		lastBuggyStr = time.Duration(lastBuggyNs).String()
-	}
-	lastFixedStr := "—"
-	if lastFixedNs > 0 {
-		lastFixedStr = time.Duration(lastFixedNs).String()
-	}
-	w.Header().Set("Content-Type", "text/html; charset=utf-8")
-	html := fmt.Sprintf(`<!DOCTYPE html>
-<html>
-<head><title>sync.Pool Lesson Dashboard</title>
-<meta http-equiv="refresh" content="2">
-<style>
-body{font-family:system-ui,sans-serif;margin:24px;background:#f8f9fa;color:#212529;}
-h1{color:#212529;}
-h2{color:#495057;font-size:1.1rem;margin-top:24px;}
-.info{background:#fff;border:1px solid #dee2e6;border-radius:8px;padding:16px;margin:12px 0;}
-.metric{background:#fff;border:1px solid #dee2e6;padding:12px 16px;margin:8px 0;border-radius:8px;}
-.nav{margin:16px 0;}
-.nav a{margin-right:16px;}
-.live{background:#fff3cd;border:1px solid #ffc107;}
-.actions a{display:inline-block;margin-right:8px;padding:8px 16px;background:#212529;color:#fff;border-radius:6px;text-decoration:none;}
-</style>
-</head>
-<body>
-<h1>sync.Pool Lesson Dashboard</h1>
-<div class="info"><strong>About:</strong> Buggy (no buffer reset) vs Fixed (reset before Put). Use actions below to generate load; dashboard updates every 2s.</div>
-<p class="nav"><a href="/">Dashboard</a> | <a href="/debug/mem">JSON MemStats</a> | <a href="/api/stats">JSON Live Stats</a></p>
-<h2>Live request metrics</h2>
-<div class="metric live"><strong>Buggy requests:</strong> %d | <strong>Last duration:</strong> %s</div>
-<div class="metric live"><strong>Fixed requests:</strong> %d | <strong>Last duration:</strong> %s</div>
-<div class="actions">
-<a href="/buggy" target="_blank">Run Buggy (GET)</a>
-<a href="/fixed" target="_blank">Run Fixed (GET)</a>
-</div>
-<h2>GC &amp; memory metrics</h2>
-<div class="metric"><strong>Alloc:</strong> %d</div>
-<div class="metric"><strong>TotalAlloc:</strong> %d</div>
-<div class="metric"><strong>NumGC:</strong> %d</div>
-<div class="metric"><strong>PauseTotalNs:</strong> %d</div>
-<div class="metric"><strong>HeapObjects:</strong> %d</div>
-<div class="metric"><strong>LastGC:</strong> %s</div>
-</body>
-</html>`,
-		buggyCnt, lastBuggyStr, fixedCnt, lastFixedStr,
-		m.Alloc, m.TotalAlloc, m.NumGC, m.PauseTotalNs, m.HeapObjects, lastGCStr)
-	fmt.Fprint(w, html)
-}
-
 func buggyHandlerWithStats(w http.ResponseWriter, r *http.Request) {
 	start := time.Now()
 	defer func() { recordBuggyStats(time.Since(start)) }()
@@ -154,17 +126,29 @@ func fixedHandlerWithStats(w http.ResponseWriter, r *http.Request) {
 	fixedHandler(w, r)
 }
 
+// handlerRequestID returns the ID assigned by the request middleware, or a
+// fresh one when a handler is called directly (as in tests).
+func handlerRequestID(r *http.Request) string {
+	if id := logging.RequestID(r.Context()); id != "" {
+		return id
+	}
+	return logging.NewRequestID()
+}
+
 func buggyHandler(w http.ResponseWriter, r *http.Request) {
 	buf := bufferPool.Get().(*bytes.Buffer)
 	defer func() { bufferPool.Put(buf) }()
+	_, span := tracing.Start(r.Context(), "read body")
 	bodyBytes, err := io.ReadAll(r.Body)
+	span.End()
 	if err != nil {
 		http.Error(w, "Failed to read body", http.StatusInternalServerError)
 		return
 	}
+	_, span = tracing.Start(r.Context(), "build response")
+	defer span.End()
 	buf.Write(bodyBytes)
-	requestID := fmt.Sprintf("REQ-%d", time.Now().UnixNano()%100000)
-	buf.WriteString(fmt.Sprintf(" [Processed by %s]", requestID))
+	buf.WriteString(fmt.Sprintf(" [Processed by %s]", handlerRequestID(r)))
 	response := fmt.Sprintf("Buggy response (len: %d): %s", buf.Len(), buf.String())
 	fmt.Fprint(w, response)
 }
@@ -172,14 +156,17 @@ func buggyHandler(w http.ResponseWriter, r *http.Request) {
 func fixedHandler(w http.ResponseWriter, r *http.Request) {
 	buf := bufferPool.Get().(*bytes.Buffer)
 	defer func() { buf.Reset(); bufferPool.Put(buf) }()
+	_, span := tracing.Start(r.Context(), "read body")
 	bodyBytes, err := io.ReadAll(r.Body)
+	span.End()
 	if err != nil {
 		http.Error(w, "Failed to read body", http.StatusInternalServerError)
 		return
 	}
+	_, span = tracing.Start(r.Context(), "build response")
+	defer span.End()
 	buf.Write(bodyBytes)
-	requestID := fmt.Sprintf("REQ-%d", time.Now().UnixNano()%100000)
-	buf.WriteString(fmt.Sprintf(" [Processed by %s]", requestID))
+	buf.WriteString(fmt.Sprintf(" [Processed by %s]", handlerRequestID(r)))
 	response := fmt.Sprintf("Fixed response (len: %d): %s", buf.Len(), buf.String())
 	fmt.Fprint(w, response)
 }
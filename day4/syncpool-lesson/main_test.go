@@ -1,10 +1,14 @@
 package main
 
 import (
+	"io"
+	"log/slog"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
+
+	"lessonkit/logging"
 )
 
 func TestDashboardHandler(t *testing.T) {
@@ -52,6 +56,23 @@ func TestFixedHandler(t *testing.T) {
 	}
 }
 
+func TestFixedHandlerUsesMiddlewareRequestID(t *testing.T) {
+	h := logging.Middleware(slog.New(slog.NewTextHandler(io.Discard, nil)), http.HandlerFunc(fixedHandler))
+	seen := map[string]bool{}
+	for i := 0; i < 100; i++ {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest("POST", "/fixed", strings.NewReader("x")))
+		id := w.Header().Get(logging.RequestIDHeader)
+		if !strings.Contains(w.Body.String(), "[Processed by "+id+"]") {
+			t.Fatalf("body %q does not carry request ID %q", w.Body.String(), id)
+		}
+		if seen[id] {
+			t.Fatalf("request ID %q reused", id)
+		}
+		seen[id] = true
+	}
+}
+
 func TestMemStatsHandler(t *testing.T) {
 	req := httptest.NewRequest("GET", "/debug/mem", nil)
 	w := httptest.NewRecorder()
@@ -63,3 +84,16 @@ func TestMemStatsHandler(t *testing.T) {
 		t.Error("memstats body missing NumGC")
 	}
 }
+
+func TestPrometheusMetrics(t *testing.T) {
+	fixedHandlerWithStats(httptest.NewRecorder(), httptest.NewRequest("POST", "/fixed", strings.NewReader("test")))
+	req := httptest.NewRequest("GET", "/metrics", nil)
+	w := httptest.NewRecorder()
+	promRegistry.Handler().ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Errorf("metrics status = %d, want 200", w.Code)
+	}
+	if !strings.Contains(w.Body.String(), `http_requests_total{handler="fixed"} `) {
+		t.Errorf("metrics body missing fixed handler counter")
+	}
+}
@@ -2,7 +2,6 @@ package arena
 
 import (
 	"fmt"
-	"reflect"
 	"unsafe"
 )
 
@@ -113,12 +112,7 @@ func BytesToString(b []byte) string {
 }
 
 // Helper to convert string to byte array without allocation (unsafe)
+// The returned slice must not be modified, since it aliases the string's memory.
 func StringToBytes(s string) []byte {
-	strHdr := (*reflect.StringHeader)(unsafe.Pointer(&s))
-	sliceHdr := &reflect.SliceHeader{
-		Data: strHdr.Data,
-		Len:  strHdr.Len,
-		Cap:  strHdr.Len,
-	}
-	return *(*[]byte)(unsafe.Pointer(sliceHdr))
+	return unsafe.Slice(unsafe.StringData(s), len(s))
 }
@@ -0,0 +1,172 @@
+// Command structcheck is a vet-style checker that flags struct types whose
+// fields could be reordered to use less memory.
+//
+// Usage:
+//
+//	structcheck [-min bytes] dir...
+//
+// Each dir is type-checked from source. Findings are printed as
+// file:line:col: message, and the exit status is 1 if any were reported.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"io"
+	"os"
+	"os/exec"
+	"runtime"
+	"sort"
+	"strings"
+
+	"lessonkit/layout"
+)
+
+// Finding is one wasteful struct layout.
+type Finding struct {
+	Pos    token.Position
+	Report layout.Report
+}
+
+func (f Finding) String() string {
+	return fmt.Sprintf("%s: struct %s is %d bytes, could be %d with field order: %s",
+		f.Pos, f.Report.Name, f.Report.Size, f.Report.OptimalSize, strings.Join(f.Report.OptimalOrder, ", "))
+}
+
+func main() {
+	minWaste := flag.Int("min", 1, "only report structs that would shrink by at least this many bytes")
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: structcheck [-min bytes] dir...\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	found := false
+	for _, dir := range flag.Args() {
+		findings, err := CheckDir(dir, runtime.GOARCH)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "structcheck: %s: %v\n", dir, err)
+			os.Exit(2)
+		}
+		for _, f := range findings {
+			if f.Report.Wasted() >= uintptr(*minWaste) {
+				fmt.Println(f)
+				found = true
+			}
+		}
+	}
+	if found {
+		os.Exit(1)
+	}
+}
+
+// CheckDir type-checks the non-test Go files in dir for arch and returns
+// every named struct type whose optimal layout is smaller than its declared one.
+func CheckDir(dir, arch string) ([]Finding, error) {
+	fset := token.NewFileSet()
+	pkgs, err := parser.ParseDir(fset, dir, func(fi os.FileInfo) bool {
+		return !strings.HasSuffix(fi.Name(), "_test.go")
+	}, 0)
+	if err != nil {
+		return nil, err
+	}
+	sizes := types.SizesFor("gc", arch)
+	if sizes == nil {
+		return nil, fmt.Errorf("unsupported GOARCH %q", arch)
+	}
+	exports, err := exportData(dir)
+	if err != nil {
+		return nil, err
+	}
+	imp := importer.ForCompiler(fset, "gc", func(path string) (io.ReadCloser, error) {
+		file, ok := exports[path]
+		if !ok {
+			return nil, fmt.Errorf("no export data for %q", path)
+		}
+		return os.Open(file)
+	})
+
+	var findings []Finding
+	for _, pkg := range pkgs {
+		files := make([]*ast.File, 0, len(pkg.Files))
+		for _, f := range pkg.Files {
+			files = append(files, f)
+		}
+		conf := types.Config{
+			Importer: imp,
+			Sizes:    sizes,
+		}
+		info := &types.Info{Defs: make(map[*ast.Ident]types.Object)}
+		if _, err := conf.Check(pkg.Name, fset, files, info); err != nil {
+			return nil, err
+		}
+		for id, obj := range info.Defs {
+			tn, ok := obj.(*types.TypeName)
+			if !ok || tn.IsAlias() {
+				continue
+			}
+			st, ok := tn.Type().Underlying().(*types.Struct)
+			if !ok || id.Name == "_" {
+				continue
+			}
+			rep := layout.AnalyzeSpecs(tn.Name(), structSpecs(st, sizes))
+			if rep.Wasted() > 0 {
+				findings = append(findings, Finding{Pos: fset.Position(id.Pos()), Report: rep})
+			}
+		}
+	}
+	sort.Slice(findings, func(i, j int) bool {
+		a, b := findings[i].Pos, findings[j].Pos
+		if a.Filename != b.Filename {
+			return a.Filename < b.Filename
+		}
+		return a.Offset < b.Offset
+	})
+	return findings, nil
+}
+
+// exportData builds the package in dir and its dependencies with the go
+// command and returns the export data file for each import path. This works
+// in module mode for any module layout, which go/build-based importers do not.
+func exportData(dir string) (map[string]string, error) {
+	cmd := exec.Command("go", "list", "-export", "-deps", "-f", "{{.ImportPath}}\t{{.Export}}", ".")
+	cmd.Dir = dir
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("go list: %v: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	exports := make(map[string]string)
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		path, file, ok := strings.Cut(line, "\t")
+		if ok && file != "" {
+			exports[path] = file
+		}
+	}
+	return exports, nil
+}
+
+func structSpecs(st *types.Struct, sizes types.Sizes) []layout.Spec {
+	specs := make([]layout.Spec, st.NumFields())
+	for i := range specs {
+		f := st.Field(i)
+		specs[i] = layout.Spec{
+			Name:  f.Name(),
+			Type:  f.Type().String(),
+			Size:  uintptr(sizes.Sizeof(f.Type())),
+			Align: uintptr(sizes.Alignof(f.Type())),
+		}
+	}
+	return specs
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCheckDir(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("go.mod", "module sample\n\ngo 1.22\n")
+	write("sample.go", `package sample
+
+type Wasteful struct {
+	A bool
+	B int64
+	C bool
+}
+
+type Tight struct {
+	B int64
+	A bool
+	C bool
+}
+`)
+
+	findings, err := CheckDir(dir, "amd64")
+	if err != nil {
+		t.Fatalf("CheckDir: %v", err)
+	}
+	if len(findings) != 1 {
+		t.Fatalf("findings = %v, want exactly Wasteful", findings)
+	}
+	f := findings[0]
+	if f.Report.Name != "Wasteful" || f.Report.Size != 24 || f.Report.OptimalSize != 16 {
+		t.Errorf("report = %+v, want Wasteful 24 -> 16", f.Report)
+	}
+	if msg := f.String(); !strings.Contains(msg, "sample.go:3:6") || !strings.Contains(msg, "B, A, C") {
+		t.Errorf("message = %q", msg)
+	}
+}
@@ -0,0 +1,44 @@
+// Command tracecollector is a local stand-in for an OpenTelemetry collector.
+// Point a lesson server at it with TRACE_EXPORT=http://localhost:4318 and
+// open http://localhost:4318/ for per-request latency breakdowns.
+//
+// Usage:
+//
+//	tracecollector [-addr :4318] [-out traces.jsonl]
+//
+// With -out, every received OTLP-JSON batch is appended to the file.
+package main
+
+import (
+	"flag"
+	"log"
+	"net/http"
+	"os"
+
+	"lessonkit/tracing"
+)
+
+func main() {
+	addr := flag.String("addr", ":4318", "listen address")
+	out := flag.String("out", "", "append received OTLP-JSON batches to this file")
+	keep := flag.Int("keep", 10000, "number of recent spans to keep for the breakdown page")
+	flag.Parse()
+
+	var c *tracing.Collector
+	if *out != "" {
+		f, err := os.OpenFile(*out, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer f.Close()
+		c = tracing.NewCollector(f, *keep)
+	} else {
+		c = tracing.NewCollector(nil, *keep)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/v1/traces", c)
+	mux.Handle("/", c)
+	log.Printf("tracecollector listening on %s", *addr)
+	log.Fatal(http.ListenAndServe(*addr, mux))
+}
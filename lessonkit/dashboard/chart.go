@@ -0,0 +1,77 @@
+package dashboard
+
+import (
+	"fmt"
+	"html/template"
+	"strings"
+)
+
+// Chart is an inline SVG sparkline. It can be used as a panel block or as a
+// table cell value.
+type Chart struct {
+	Values        []float64
+	Width, Height int
+	// Color is a CSS hex color such as "#0f4"; anything else falls back to
+	// the theme accent.
+	Color string
+}
+
+func (Chart) block() {}
+
+// SVG renders the series as a polyline scaled to fit Width x Height. An
+// empty series renders an empty frame. The markup is built only from numbers
+// and a validated color, so it is safe to emit unescaped.
+func (c Chart) SVG() template.HTML {
+	w, h := c.Width, c.Height
+	if w <= 0 {
+		w = 120
+	}
+	if h <= 0 {
+		h = 24
+	}
+	color := "currentColor"
+	if isHexColor(c.Color) {
+		color = c.Color
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg class="spark" width="%d" height="%d" viewBox="0 0 %d %d" xmlns="http://www.w3.org/2000/svg">`,
+		w, h, w, h)
+	if len(c.Values) > 0 {
+		maxV := 0.0
+		for _, v := range c.Values {
+			if v > maxV {
+				maxV = v
+			}
+		}
+		step := 0.0
+		if len(c.Values) > 1 {
+			step = float64(w-2) / float64(len(c.Values)-1)
+		}
+		fmt.Fprintf(&b, `<polyline fill="none" stroke="%s" stroke-width="1.5" points="`, color)
+		for i, v := range c.Values {
+			y := float64(h - 1)
+			if maxV > 0 {
+				y = float64(h-1) - v/maxV*float64(h-2)
+			}
+			if i > 0 {
+				b.WriteByte(' ')
+			}
+			fmt.Fprintf(&b, "%.1f,%.1f", 1+float64(i)*step, y)
+		}
+		b.WriteString(`"/>`)
+	}
+	b.WriteString(`</svg>`)
+	return template.HTML(b.String())
+}
+
+func isHexColor(s string) bool {
+	if len(s) != 4 && len(s) != 7 || s[0] != '#' {
+		return false
+	}
+	for _, r := range s[1:] {
+		if !strings.ContainsRune("0123456789abcdefABCDEF", r) {
+			return false
+		}
+	}
+	return true
+}
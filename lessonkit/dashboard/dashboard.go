@@ -0,0 +1,227 @@
+// Package dashboard renders the lesson servers' HTML dashboards from
+// registered panels. Pages are built with html/template, so every value is
+// escaped, and panels refresh in place over Server-Sent Events instead of
+// reloading the whole page.
+//
+// A lesson builds one Dashboard, adds panels whose Render funcs read its
+// live state, and mounts the page and its stream:
+//
+//	dash := dashboard.New("GC Hidden Cost Dashboard", dashboard.Light)
+//	dash.Add(dashboard.Panel{ID: "requests", Title: "Live request metrics", Render: requestCards})
+//	mux.Handle("/", dash)
+//	mux.Handle(dash.StreamPath, dash.Stream())
+package dashboard
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Theme holds the handful of colors the page stylesheet uses.
+type Theme struct {
+	Background, Panel, Border, Text, Muted, Accent, Live string
+}
+
+var (
+	// Dark is the day2 palette.
+	Dark = Theme{Background: "#1a1a2e", Panel: "#16213e", Border: "#1a1a2e", Text: "#eee", Muted: "#aaa", Accent: "#0f4", Live: "#16213e"}
+	// Light is the day3/day4 palette.
+	Light = Theme{Background: "#f8f9fa", Panel: "#fff", Border: "#dee2e6", Text: "#212529", Muted: "#495057", Accent: "#212529", Live: "#fff3cd"}
+)
+
+// Link is a navigation or action link.
+type Link struct {
+	Label  string
+	URL    string
+	NewTab bool
+}
+
+// Block is one piece of panel content: Cards, *Table, Links, Text, Chart or
+// *Form.
+type Block interface{ block() }
+
+// Card is a single labelled value. Live cards are highlighted.
+type Card struct {
+	Label string
+	Value any
+	Live  bool
+}
+
+// Cards renders one metric card per line.
+type Cards []Card
+
+// Table renders rows under column headers. Cell values are escaped text,
+// except Chart values, which render as inline SVG.
+type Table struct {
+	Columns []string
+	Rows    [][]any
+}
+
+// Links renders as a row of buttons.
+type Links []Link
+
+// Text is a paragraph of escaped text.
+type Text string
+
+// Form renders an inline form of text inputs and a submit button. Method
+// defaults to POST.
+type Form struct {
+	Action string
+	Method string
+	Fields []Field
+	Submit string
+}
+
+// Field is one form input. Hidden fields carry Value without a label.
+type Field struct {
+	Name        string
+	Label       string
+	Value       string
+	Placeholder string
+	Hidden      bool
+}
+
+func (Cards) block()  {}
+func (*Table) block() {}
+func (Links) block()  {}
+func (Text) block()   {}
+func (*Form) block()  {}
+
+// Panel is a titled section of the page. Render is called for the initial
+// page and again on every stream tick unless Static is set.
+type Panel struct {
+	ID     string
+	Title  string
+	Static bool
+	Render func() []Block
+}
+
+// Dashboard is an http.Handler serving the page; Stream serves its updates.
+type Dashboard struct {
+	Title string
+	Intro string
+	Theme Theme
+	Nav   []Link
+	// Interval between stream updates. Defaults to 2s.
+	Interval time.Duration
+	// StreamPath is where the page's EventSource connects. Set it to "" for
+	// one-off pages (such as experiment results) that should not refresh.
+	StreamPath string
+	// EventsPath, when set, adds live charts fed by the Events stream
+	// mounted at that path.
+	EventsPath string
+
+	mu     sync.RWMutex
+	panels []Panel
+
+	closeOnce sync.Once
+	done      chan struct{}
+}
+
+// New returns an empty dashboard streaming from /dashboard/stream.
+func New(title string, theme Theme) *Dashboard {
+	return &Dashboard{
+		Title:      title,
+		Theme:      theme,
+		Interval:   2 * time.Second,
+		StreamPath: "/dashboard/stream",
+		done:       make(chan struct{}),
+	}
+}
+
+// Add appends a panel. Panel IDs must be unique.
+func (d *Dashboard) Add(p Panel) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for _, q := range d.panels {
+		if q.ID == p.ID {
+			panic(fmt.Sprintf("dashboard: duplicate panel ID %q", p.ID))
+		}
+	}
+	d.panels = append(d.panels, p)
+}
+
+func (d *Dashboard) panelList() []Panel {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return append([]Panel(nil), d.panels...)
+}
+
+// ServeHTTP renders the full page.
+func (d *Dashboard) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var body bytes.Buffer
+	if err := d.renderPage(&body); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(body.Bytes())
+}
+
+type pageData struct {
+	*Dashboard
+	Panels       []panelData
+	IntervalSecs int
+}
+
+type panelData struct {
+	ID, Title string
+	Body      template.HTML
+}
+
+func (d *Dashboard) renderPage(buf *bytes.Buffer) error {
+	data := pageData{Dashboard: d, IntervalSecs: int(d.Interval / time.Second)}
+	if data.IntervalSecs < 1 {
+		data.IntervalSecs = 1
+	}
+	for _, p := range d.panelList() {
+		body, err := renderPanel(p)
+		if err != nil {
+			return err
+		}
+		data.Panels = append(data.Panels, panelData{ID: p.ID, Title: p.Title, Body: body})
+	}
+	return pageTmpl.Execute(buf, data)
+}
+
+// renderPanel renders a panel's blocks through the block templates. The
+// result is trusted HTML because html/template escaped every value in it.
+func renderPanel(p Panel) (template.HTML, error) {
+	if p.Render == nil {
+		return "", nil
+	}
+	var buf bytes.Buffer
+	for _, b := range p.Render() {
+		if err := blockTmpl.ExecuteTemplate(&buf, blockName(b), b); err != nil {
+			return "", fmt.Errorf("panel %s: %w", p.ID, err)
+		}
+	}
+	return template.HTML(buf.String()), nil
+}
+
+func blockName(b Block) string {
+	switch b.(type) {
+	case Cards:
+		return "cards"
+	case *Table:
+		return "table"
+	case Links:
+		return "links"
+	case Text:
+		return "text"
+	case Chart:
+		return "chart"
+	case *Form:
+		return "form"
+	}
+	return "unknown"
+}
+
+// Close ends open streams so http.Server.Shutdown does not wait on them.
+func (d *Dashboard) Close() {
+	d.closeOnce.Do(func() { close(d.done) })
+}
@@ -0,0 +1,267 @@
+package dashboard
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testDashboard() *Dashboard {
+	d := New("Test <Dashboard>", Dark)
+	d.Intro = `Compare "naive" & pooled`
+	d.Nav = []Link{{Label: "Home", URL: "/"}}
+	d.Add(Panel{ID: "cards", Title: "Cards", Render: func() []Block {
+		return []Block{Cards{{Label: "Requests", Value: 42, Live: true}, {Label: "Name", Value: "<b>x</b>"}}}
+	}})
+	d.Add(Panel{ID: "table", Title: "Table", Render: func() []Block {
+		return []Block{&Table{
+			Columns: []string{"Endpoint", "Trend"},
+			Rows:    [][]any{{"heap", Chart{Values: []float64{1, 2, 3}, Color: "#0f4"}}},
+		}}
+	}})
+	d.Add(Panel{ID: "actions", Static: true, Render: func() []Block {
+		return []Block{Links{{Label: "Run", URL: "/run?size=4096", NewTab: true}}, Text("Click to run.")}
+	}})
+	return d
+}
+
+func TestPageRendersAndEscapes(t *testing.T) {
+	w := httptest.NewRecorder()
+	testDashboard().ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	body := w.Body.String()
+
+	for _, want := range []string{
+		"<h1>Test &lt;Dashboard&gt;</h1>",
+		"Compare &#34;naive&#34; &amp; pooled",
+		`<strong>Requests:</strong> 42`,
+		"&lt;b&gt;x&lt;/b&gt;",
+		`<polyline fill="none" stroke="#0f4"`,
+		`<a href="/run?size=4096" target="_blank">Run</a>`,
+		`<div id="panel-table-body">`,
+		`new EventSource("/dashboard/stream")`,
+		"background:#1a1a2e",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("page missing %q", want)
+		}
+	}
+	if strings.Contains(body, "<b>x</b>") {
+		t.Error("card value was not escaped")
+	}
+}
+
+func TestChartRejectsUnsafeColor(t *testing.T) {
+	svg := string(Chart{Values: []float64{1}, Color: `red" onload="alert(1)`}.SVG())
+	if strings.Contains(svg, "onload") || !strings.Contains(svg, `stroke="currentColor"`) {
+		t.Errorf("unsafe color leaked into SVG: %s", svg)
+	}
+	if strings.Contains(string(Chart{}.SVG()), "<polyline") {
+		t.Error("empty chart should not draw a line")
+	}
+}
+
+func TestDuplicatePanelPanics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("duplicate panel ID did not panic")
+		}
+	}()
+	d := New("x", Light)
+	d.Add(Panel{ID: "a"})
+	d.Add(Panel{ID: "a"})
+}
+
+func TestStreamSendsLivePanels(t *testing.T) {
+	d := testDashboard()
+	d.Interval = 10 * time.Millisecond
+	srv := httptest.NewServer(d.Stream())
+	defer srv.Close()
+	defer d.Close()
+
+	resp, err := http.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("Content-Type = %q", ct)
+	}
+
+	got := map[string]string{}
+	sc := bufio.NewScanner(resp.Body)
+	for sc.Scan() && len(got) < 2 {
+		line := sc.Text()
+		if !strings.HasPrefix(line, "data: ") {
+			continue
+		}
+		var m struct{ ID, HTML string }
+		if err := json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &m); err != nil {
+			t.Fatalf("bad event %q: %v", line, err)
+		}
+		got[m.ID] = m.HTML
+	}
+	if !strings.Contains(got["cards"], "42") || !strings.Contains(got["table"], "<svg") {
+		t.Errorf("stream events = %v", got)
+	}
+	if _, ok := got["actions"]; ok {
+		t.Error("static panel was streamed")
+	}
+}
+
+// noFlushWriter is a ResponseWriter without Flush that records every
+// WriteHeader call.
+type noFlushWriter struct {
+	h     http.Header
+	codes []int
+}
+
+func (w *noFlushWriter) Header() http.Header         { return w.h }
+func (w *noFlushWriter) Write(p []byte) (int, error) { return len(p), nil }
+func (w *noFlushWriter) WriteHeader(code int)        { w.codes = append(w.codes, code) }
+
+func TestStreamWithoutFlusher(t *testing.T) {
+	d := testDashboard()
+	defer d.Close()
+	w := &noFlushWriter{h: http.Header{}}
+	d.Stream().ServeHTTP(w, httptest.NewRequest("GET", "/dashboard/stream", nil))
+	if len(w.codes) != 1 || w.codes[0] != http.StatusInternalServerError {
+		t.Errorf("WriteHeader calls = %v, want one 500", w.codes)
+	}
+	if ct := w.h.Get("Content-Type"); strings.Contains(ct, "event-stream") {
+		t.Errorf("error response kept Content-Type %q", ct)
+	}
+}
+
+func TestEventsStreamsStats(t *testing.T) {
+	d := New("x", Light)
+	requests := uint64(0)
+	stats := func() map[string]HandlerStats {
+		requests += 10
+		return map[string]HandlerStats{"naive": {Requests: requests, LastNs: 1500}}
+	}
+	srv := httptest.NewServer(d.Events(stats))
+	defer srv.Close()
+	defer d.Close()
+
+	resp, err := http.Get(srv.URL + "?interval=50")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	sink := make([][]byte, 0, 1024)
+	for i := 0; i < 1024; i++ {
+		sink = append(sink, make([]byte, 1024))
+	}
+	runtime.GC()
+
+	sc := bufio.NewScanner(resp.Body)
+	var events []EventSample
+	for sc.Scan() && len(events) < 2 {
+		line := sc.Text()
+		if !strings.HasPrefix(line, "data: ") {
+			continue
+		}
+		var ev EventSample
+		if err := json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &ev); err != nil {
+			t.Fatalf("bad event %q: %v", line, err)
+		}
+		events = append(events, ev)
+	}
+	runtime.KeepAlive(sink)
+	if len(events) < 2 {
+		t.Fatalf("got %d events, want 2", len(events))
+	}
+	var gcs uint32
+	for _, ev := range events {
+		gcs += ev.Mem.NumGCDelta
+		if len(ev.GC) != int(ev.Mem.NumGCDelta) {
+			t.Errorf("event lists %d GC cycles, delta says %d", len(ev.GC), ev.Mem.NumGCDelta)
+		}
+		if ev.Mem.Alloc == 0 {
+			t.Error("heap alloc gauge is zero")
+		}
+		h := ev.Handlers["naive"]
+		if h.LastNs != 1500 || h.PerSec <= 0 {
+			t.Errorf("naive handler sample = %+v", h)
+		}
+	}
+	if gcs == 0 {
+		t.Error("forced GC did not show up in any event")
+	}
+}
+
+func TestEventsRejectsBadInterval(t *testing.T) {
+	w := httptest.NewRecorder()
+	New("x", Light).Events(nil).ServeHTTP(w, httptest.NewRequest("GET", "/events?interval=1", nil))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("status = %d, want 400", w.Code)
+	}
+}
+
+func TestPageIncludesLiveChartsWhenEventsEnabled(t *testing.T) {
+	d := New("x", Light)
+	w := httptest.NewRecorder()
+	d.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	if strings.Contains(w.Body.String(), `id="live"`) {
+		t.Error("live charts rendered without EventsPath")
+	}
+	d.EventsPath = "/events"
+	w = httptest.NewRecorder()
+	d.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	if !strings.Contains(w.Body.String(), `new EventSource("/events")`) || !strings.Contains(w.Body.String(), `id="live-handlers"`) {
+		t.Error("page missing live charts wired to /events")
+	}
+}
+
+func TestFormAndStaticPage(t *testing.T) {
+	d := New("Result", Light)
+	d.StreamPath = ""
+	d.Add(Panel{ID: "f", Render: func() []Block {
+		return []Block{&Form{Action: "/admin/gc", Submit: "Apply", Fields: []Field{
+			{Name: "gogc", Label: "GOGC", Value: `100"><script>`},
+			{Name: "redirect", Value: "/", Hidden: true},
+		}}}
+	}})
+	w := httptest.NewRecorder()
+	d.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	body := w.Body.String()
+	for _, want := range []string{
+		`<form class="metric controls" method="post" action="/admin/gc">`,
+		`<input type="hidden" name="redirect" value="/">`,
+		`value="100&#34;&gt;&lt;script&gt;"`,
+		`<button type="submit">Apply</button>`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("page missing %q", want)
+		}
+	}
+	if strings.Contains(body, "EventSource") || strings.Contains(body, "http-equiv") {
+		t.Error("page without StreamPath should not refresh")
+	}
+}
+
+func TestGCCyclesSinceUnrollsPauseRing(t *testing.T) {
+	var m runtime.MemStats
+	m.NumGC = 300 // the ring has wrapped; cycles 45..300 remain
+	for n := uint32(1); n <= m.NumGC; n++ {
+		i := (n - 1) % uint32(len(m.PauseNs))
+		m.PauseNs[i] = uint64(n)
+		m.PauseEnd[i] = uint64(n) * uint64(time.Millisecond)
+	}
+	got := GCCyclesSince(290, &m)
+	if len(got) != 10 || got[0].Num != 291 || got[9].Num != 300 || got[9].PauseNs != 300 || got[9].End.UnixMilli() != 300 {
+		t.Errorf("cycles since 290 = %+v", got)
+	}
+	if got := GCCyclesSince(0, &m); len(got) != len(m.PauseNs) || got[0].Num != 45 {
+		t.Errorf("cycles since 0 = %d starting at %d, want 256 starting at 45", len(got), got[0].Num)
+	}
+	if got := GCCyclesSince(300, &m); got != nil {
+		t.Errorf("no new cycles = %+v", got)
+	}
+}
@@ -0,0 +1,203 @@
+package dashboard
+
+import (
+	"encoding/json"
+	"net/http"
+	"runtime"
+	"strconv"
+	"time"
+)
+
+// Bounds for the ?interval= query parameter of Dashboard.Events, in milliseconds.
+const (
+	DefaultEventInterval = 500
+	MinEventInterval     = 50
+	MaxEventInterval     = 10000
+)
+
+// HandlerStats is a lesson's per-handler request counter and latency.
+// Lessons that keep latency histograms set P99Ns, which the live table
+// shows in place of LastNs.
+type HandlerStats struct {
+	Requests uint64 `json:"requests"`
+	LastNs   int64  `json:"last_ns"`
+	P99Ns    int64  `json:"p99_ns,omitempty"`
+}
+
+// HandlerSample is one handler's stats plus its rate over the last interval.
+type HandlerSample struct {
+	HandlerStats
+	PerSec float64 `json:"per_sec"`
+}
+
+// MemSample carries current heap gauges and counter deltas over the last
+// interval, all taken from runtime.MemStats.
+type MemSample struct {
+	Alloc         uint64  `json:"alloc"`
+	HeapInuse     uint64  `json:"heap_inuse"`
+	HeapObjects   uint64  `json:"heap_objects"`
+	Sys           uint64  `json:"sys"`
+	NextGC        uint64  `json:"next_gc"`
+	GCCPUFraction float64 `json:"gc_cpu_fraction"`
+
+	TotalAllocDelta uint64  `json:"total_alloc_delta"`
+	AllocPerSec     float64 `json:"alloc_per_sec"`
+	MallocsDelta    uint64  `json:"mallocs_delta"`
+	FreesDelta      uint64  `json:"frees_delta"`
+	NumGCDelta      uint32  `json:"num_gc_delta"`
+	PauseNsDelta    uint64  `json:"pause_ns_delta"`
+}
+
+// GCEvent is one completed GC cycle observed during the interval.
+type GCEvent struct {
+	Num       uint32 `json:"num"`
+	EndUnixMs int64  `json:"end_unix_ms"`
+	PauseNs   uint64 `json:"pause_ns"`
+}
+
+// EventSample is the payload of each "stats" event on the /events stream.
+type EventSample struct {
+	UnixMs     int64                    `json:"unix_ms"`
+	IntervalMs int64                    `json:"interval_ms"`
+	Mem        MemSample                `json:"mem"`
+	GC         []GCEvent                `json:"gc"`
+	Handlers   map[string]HandlerSample `json:"handlers"`
+}
+
+// sampler turns successive MemStats and handler snapshots into deltas. Each
+// stream connection owns one so clients with different intervals do not
+// interfere.
+type sampler struct {
+	stats    func() map[string]HandlerStats
+	prevMem  runtime.MemStats
+	prevReqs map[string]uint64
+	prevAt   time.Time
+}
+
+func newSampler(stats func() map[string]HandlerStats) *sampler {
+	s := &sampler{stats: stats, prevReqs: map[string]uint64{}}
+	runtime.ReadMemStats(&s.prevMem)
+	s.prevAt = time.Now()
+	if stats != nil {
+		for name, h := range stats() {
+			s.prevReqs[name] = h.Requests
+		}
+	}
+	return s
+}
+
+func (s *sampler) next() EventSample {
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+	now := time.Now()
+	secs := now.Sub(s.prevAt).Seconds()
+	p := &s.prevMem
+
+	out := EventSample{
+		UnixMs:     now.UnixMilli(),
+		IntervalMs: now.Sub(s.prevAt).Milliseconds(),
+		Mem: MemSample{
+			Alloc:           m.Alloc,
+			HeapInuse:       m.HeapInuse,
+			HeapObjects:     m.HeapObjects,
+			Sys:             m.Sys,
+			NextGC:          m.NextGC,
+			GCCPUFraction:   m.GCCPUFraction,
+			TotalAllocDelta: m.TotalAlloc - p.TotalAlloc,
+			MallocsDelta:    m.Mallocs - p.Mallocs,
+			FreesDelta:      m.Frees - p.Frees,
+			NumGCDelta:      m.NumGC - p.NumGC,
+			PauseNsDelta:    m.PauseTotalNs - p.PauseTotalNs,
+		},
+		GC:       gcEvents(p.NumGC, &m),
+		Handlers: map[string]HandlerSample{},
+	}
+	if secs > 0 {
+		out.Mem.AllocPerSec = float64(out.Mem.TotalAllocDelta) / secs
+	}
+	if s.stats != nil {
+		for name, h := range s.stats() {
+			hs := HandlerSample{HandlerStats: h}
+			if secs > 0 && h.Requests >= s.prevReqs[name] {
+				hs.PerSec = float64(h.Requests-s.prevReqs[name]) / secs
+			}
+			out.Handlers[name] = hs
+			s.prevReqs[name] = h.Requests
+		}
+	}
+	s.prevMem, s.prevAt = m, now
+	return out
+}
+
+// GCCycle is one completed GC cycle from MemStats' pause ring.
+type GCCycle struct {
+	Num     uint32    `json:"num"`
+	End     time.Time `json:"end"`
+	PauseNs uint64    `json:"pause_ns"`
+}
+
+// GCCyclesSince unrolls MemStats' 256-entry PauseNs/PauseEnd ring into the
+// cycles after prevNum, oldest first. Cycles that have already rotated out
+// of the ring are skipped.
+func GCCyclesSince(prevNum uint32, m *runtime.MemStats) []GCCycle {
+	first := prevNum + 1
+	if m.NumGC > uint32(len(m.PauseNs)) && first <= m.NumGC-uint32(len(m.PauseNs)) {
+		first = m.NumGC - uint32(len(m.PauseNs)) + 1
+	}
+	var cycles []GCCycle
+	for n := first; n <= m.NumGC && n > prevNum; n++ {
+		i := (n + uint32(len(m.PauseNs)) - 1) % uint32(len(m.PauseNs))
+		cycles = append(cycles, GCCycle{Num: n, End: time.Unix(0, int64(m.PauseEnd[i])), PauseNs: m.PauseNs[i]})
+	}
+	return cycles
+}
+
+// gcEvents lists cycles after prevNum as stream events.
+func gcEvents(prevNum uint32, m *runtime.MemStats) []GCEvent {
+	var events []GCEvent
+	for _, c := range GCCyclesSince(prevNum, m) {
+		events = append(events, GCEvent{Num: c.Num, EndUnixMs: c.End.UnixMilli(), PauseNs: c.PauseNs})
+	}
+	return events
+}
+
+// Events returns the handler for EventsPath. It streams a "stats" event
+// every ?interval= milliseconds (default 500) with MemStats deltas, GC
+// cycles completed since the last event, and per-handler request stats from
+// stats, which may be nil. The page's live charts consume it.
+func (d *Dashboard) Events(stats func() map[string]HandlerStats) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ms := DefaultEventInterval
+		if s := r.URL.Query().Get("interval"); s != "" {
+			n, err := strconv.Atoi(s)
+			if err != nil || n < MinEventInterval || n > MaxEventInterval {
+				http.Error(w, "invalid 'interval' parameter (milliseconds, 50-10000)", http.StatusBadRequest)
+				return
+			}
+			ms = n
+		}
+		// Take the baseline before the headers go out, so anything the client
+		// does after connecting lands in the first event.
+		s := newSampler(stats)
+		ew, err := NewEventWriter(w)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		tick := time.NewTicker(time.Duration(ms) * time.Millisecond)
+		defer tick.Stop()
+		for {
+			select {
+			case <-r.Context().Done():
+				return
+			case <-d.done:
+				return
+			case <-tick.C:
+			}
+			b, _ := json.Marshal(s.next())
+			if err := ew.Send("stats", b); err != nil {
+				return
+			}
+		}
+	})
+}
@@ -0,0 +1,98 @@
+package dashboard
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+)
+
+// EventWriter writes Server-Sent Events to a streaming response.
+type EventWriter struct {
+	w  http.ResponseWriter
+	rc *http.ResponseController
+}
+
+// NewEventWriter sets the SSE headers and lifts the server's write deadline
+// for this response, since a stream outlives any per-request WriteTimeout.
+// The first flush commits the 200; if the response cannot be flushed,
+// nothing has been written and the caller can still send an error.
+func NewEventWriter(w http.ResponseWriter) (*EventWriter, error) {
+	rc := http.NewResponseController(w)
+	rc.SetWriteDeadline(time.Time{})
+	h := w.Header()
+	h.Set("Content-Type", "text/event-stream")
+	h.Set("Cache-Control", "no-cache")
+	h.Set("X-Accel-Buffering", "no")
+	if err := rc.Flush(); err != nil {
+		if errors.Is(err, http.ErrNotSupported) {
+			h.Del("Content-Type")
+			h.Del("Cache-Control")
+			h.Del("X-Accel-Buffering")
+			return nil, errors.New("dashboard: response does not support streaming")
+		}
+		return nil, err
+	}
+	return &EventWriter{w: w, rc: rc}, nil
+}
+
+// Send writes one event and flushes it. data must not contain newlines;
+// JSON from encoding/json never does.
+func (e *EventWriter) Send(event string, data []byte) error {
+	var b bytes.Buffer
+	if event != "" {
+		b.WriteString("event: ")
+		b.WriteString(event)
+		b.WriteByte('\n')
+	}
+	b.WriteString("data: ")
+	b.Write(data)
+	b.WriteString("\n\n")
+	if _, err := e.w.Write(b.Bytes()); err != nil {
+		return err
+	}
+	return e.rc.Flush()
+}
+
+// Stream returns the handler for StreamPath. Each tick it re-renders the
+// non-static panels and sends a "panel" event ({"id","html"}) for those
+// whose HTML changed since the last event on this connection.
+func (d *Dashboard) Stream() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ew, err := NewEventWriter(w)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		tick := time.NewTicker(d.Interval)
+		defer tick.Stop()
+		sent := map[string]string{}
+		for {
+			for _, p := range d.panelList() {
+				if p.Static {
+					continue
+				}
+				body, err := renderPanel(p)
+				if err != nil || sent[p.ID] == string(body) {
+					continue
+				}
+				msg, _ := json.Marshal(struct {
+					ID   string `json:"id"`
+					HTML string `json:"html"`
+				}{p.ID, string(body)})
+				if err := ew.Send("panel", msg); err != nil {
+					return
+				}
+				sent[p.ID] = string(body)
+			}
+			select {
+			case <-r.Context().Done():
+				return
+			case <-d.done:
+				return
+			case <-tick.C:
+			}
+		}
+	})
+}
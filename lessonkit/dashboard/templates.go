@@ -0,0 +1,159 @@
+package dashboard
+
+import "html/template"
+
+var funcs = template.FuncMap{
+	// cell lets Chart values in table rows render as SVG; everything else
+	// is printed (and escaped) as usual.
+	"cell": func(v any) any {
+		if c, ok := v.(Chart); ok {
+			return c.SVG()
+		}
+		return v
+	},
+}
+
+var blockTmpl = template.Must(template.New("blocks").Funcs(funcs).Parse(`
+{{define "cards"}}{{range .}}<div class="metric{{if .Live}} live{{end}}"><strong>{{.Label}}:</strong> {{cell .Value}}</div>
+{{end}}{{end}}
+{{define "table"}}<table class="metric">
+<tr>{{range .Columns}}<th>{{.}}</th>{{end}}</tr>
+{{range .Rows}}<tr>{{range .}}<td>{{cell .}}</td>{{end}}</tr>
+{{end}}</table>
+{{end}}
+{{define "links"}}<div class="actions">{{range .}}<a href="{{.URL}}"{{if .NewTab}} target="_blank"{{end}}>{{.Label}}</a>{{end}}</div>
+{{end}}
+{{define "text"}}<p>{{.}}</p>
+{{end}}
+{{define "chart"}}<div class="metric">{{.SVG}}</div>
+{{end}}
+{{define "form"}}<form class="metric controls" method="{{or .Method "post"}}" action="{{.Action}}">
+{{range .Fields}}{{if .Hidden}}<input type="hidden" name="{{.Name}}" value="{{.Value}}">
+{{else}}<label>{{.Label}} <input name="{{.Name}}" value="{{.Value}}" placeholder="{{.Placeholder}}"></label>
+{{end}}{{end}}<button type="submit">{{.Submit}}</button>
+</form>
+{{end}}
+{{define "unknown"}}{{end}}
+`))
+
+var pageTmpl = template.Must(template.New("page").Parse(`<!DOCTYPE html>
+<html>
+<head><title>{{.Title}}</title>
+{{if .StreamPath}}<noscript><meta http-equiv="refresh" content="{{.IntervalSecs}}"></noscript>
+{{end}}
+<style>
+body{font-family:system-ui,sans-serif;margin:24px;background:{{.Theme.Background}};color:{{.Theme.Text}};}
+h1{color:{{.Theme.Accent}};font-weight:600;}
+h2{color:{{.Theme.Muted}};font-size:1.1rem;margin-top:24px;}
+.info{background:{{.Theme.Panel}};border:1px solid {{.Theme.Border}};border-radius:8px;padding:16px;margin:12px 0;line-height:1.5;}
+.metric{background:{{.Theme.Panel}};border:1px solid {{.Theme.Border}};padding:12px 16px;margin:8px 0;border-radius:8px;}
+.metric strong{color:{{.Theme.Accent}};}
+.live{background:{{.Theme.Live}};}
+.nav{margin:16px 0;}
+.nav a{color:{{.Theme.Accent}};margin-right:12px;}
+.actions{margin:12px 0;}
+.actions a{display:inline-block;margin:0 8px 8px 0;padding:8px 16px;background:{{.Theme.Accent}};color:{{.Theme.Background}};border-radius:6px;text-decoration:none;}
+table.metric{border-collapse:collapse;width:100%;}
+table.metric th,table.metric td{text-align:left;padding:4px 8px;border-bottom:1px solid {{.Theme.Border}};}
+.controls label{margin-right:12px;}
+.controls input{width:8em;padding:4px;}
+.controls button{padding:6px 14px;background:{{.Theme.Accent}};color:{{.Theme.Background}};border:0;border-radius:6px;}
+#status{color:{{.Theme.Muted}};font-size:0.9rem;}
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+{{if .Intro}}<div class="info">{{.Intro}}</div>
+{{end}}{{if .Nav}}<p class="nav">{{range $i, $l := .Nav}}{{if $i}} | {{end}}<a href="{{$l.URL}}"{{if $l.NewTab}} target="_blank"{{end}}>{{$l.Label}}</a>{{end}}</p>
+{{end}}{{range .Panels}}<section id="panel-{{.ID}}">
+{{if .Title}}<h2>{{.Title}}</h2>
+{{end}}<div id="panel-{{.ID}}-body">{{.Body}}</div>
+</section>
+{{end}}{{if .EventsPath}}<section id="live">
+<h2>Live runtime</h2>
+<div class="metric"><strong>Heap alloc:</strong> <span id="live-alloc-v">—</span><br><svg id="live-alloc" class="spark" width="600" height="60"></svg></div>
+<div class="metric"><strong>Alloc rate:</strong> <span id="live-rate-v">—</span><br><svg id="live-rate" class="spark" width="600" height="60"></svg></div>
+<div class="metric"><strong>GC pause per interval:</strong> <span id="live-pause-v">—</span><br><svg id="live-pause" class="spark" width="600" height="60"></svg></div>
+<table class="metric" id="live-handlers"><tr><th>Handler</th><th>Req/s</th><th></th><th>Requests</th><th>Latency</th></tr></table>
+<div class="metric"><strong>Recent GC cycles:</strong> <span id="live-gc">none yet</span></div>
+</section>
+{{end}}{{if .StreamPath}}<p id="status"><em>Updates every {{.IntervalSecs}}s.</em></p>
+<script>
+(function () {
+  if (!window.EventSource) return;
+  var status = document.getElementById("status");
+  var es = new EventSource({{.StreamPath}});
+  es.addEventListener("panel", function (e) {
+    var m = JSON.parse(e.data);
+    var el = document.getElementById("panel-" + m.id + "-body");
+    if (el) el.innerHTML = m.html;
+  });
+  es.onopen = function () { status.textContent = "Live: updates stream every {{.IntervalSecs}}s."; };
+  es.onerror = function () { status.textContent = "Stream disconnected, retrying..."; };
+})();
+</script>
+{{end}}{{if .EventsPath}}<script>
+(function () {
+  if (!window.EventSource) return;
+  var N = 120, accent = {{.Theme.Accent}}, series = {}, rows = {}, gcLog = [];
+  function push(key, v) {
+    var s = series[key] || (series[key] = []);
+    s.push(v);
+    if (s.length > N) s.shift();
+    return s;
+  }
+  function draw(svg, values, color) {
+    var w = svg.width.baseVal.value, h = svg.height.baseVal.value, max = 0, pts = [];
+    values.forEach(function (v) { if (v > max) max = v; });
+    values.forEach(function (v, i) {
+      var x = 1 + i * (w - 2) / Math.max(N - 1, 1);
+      var y = max > 0 ? (h - 1) - v / max * (h - 2) : h - 1;
+      pts.push(x.toFixed(1) + "," + y.toFixed(1));
+    });
+    svg.innerHTML = '<polyline fill="none" stroke-width="1.5" stroke="' + color + '" points="' + pts.join(" ") + '"/>';
+  }
+  function bytes(n) {
+    var u = ["B", "KB", "MB", "GB"], i = 0;
+    while (n >= 1024 && i < u.length - 1) { n /= 1024; i++; }
+    return n.toFixed(1) + " " + u[i];
+  }
+  function ms(ns) { return (ns / 1e6).toFixed(3) + " ms"; }
+  function handlerRow(name) {
+    if (rows[name]) return rows[name];
+    var tr = document.createElement("tr"), cells = [];
+    for (var i = 0; i < 5; i++) cells.push(tr.insertCell());
+    cells[0].textContent = name;
+    var svg = document.createElementNS("http://www.w3.org/2000/svg", "svg");
+    svg.setAttribute("class", "spark"); svg.setAttribute("width", "240"); svg.setAttribute("height", "24");
+    cells[2].appendChild(svg);
+    document.getElementById("live-handlers").appendChild(tr);
+    return rows[name] = {cells: cells, svg: svg};
+  }
+  var es = new EventSource({{.EventsPath}});
+  es.addEventListener("stats", function (e) {
+    var d = JSON.parse(e.data), m = d.mem;
+    draw(document.getElementById("live-alloc"), push("alloc", m.alloc), accent);
+    document.getElementById("live-alloc-v").textContent = bytes(m.alloc) + " (next GC at " + bytes(m.next_gc) + ")";
+    draw(document.getElementById("live-rate"), push("rate", m.alloc_per_sec), accent);
+    document.getElementById("live-rate-v").textContent = bytes(m.alloc_per_sec) + "/s, " + m.mallocs_delta + " mallocs";
+    draw(document.getElementById("live-pause"), push("pause", m.pause_ns_delta), "#f84");
+    document.getElementById("live-pause-v").textContent = ms(m.pause_ns_delta) + " over " + m.num_gc_delta + " cycles";
+    Object.keys(d.handlers).sort().forEach(function (name) {
+      var h = d.handlers[name], row = handlerRow(name);
+      draw(row.svg, push("h:" + name, h.per_sec), accent);
+      row.cells[1].textContent = h.per_sec.toFixed(1);
+      row.cells[3].textContent = h.requests;
+      row.cells[4].textContent = h.p99_ns > 0 ? "p99 " + ms(h.p99_ns) : h.last_ns > 0 ? ms(h.last_ns) : "—";
+    });
+    (d.gc || []).forEach(function (g) {
+      gcLog.unshift("#" + g.num + " " + ms(g.pause_ns) + " at " + new Date(g.end_unix_ms).toLocaleTimeString());
+    });
+    gcLog = gcLog.slice(0, 8);
+    if (gcLog.length) document.getElementById("live-gc").textContent = gcLog.join(" | ");
+  });
+})();
+</script>
+{{end}}
+</body>
+</html>
+`))
@@ -0,0 +1,3 @@
+module lessonkit
+
+go 1.22
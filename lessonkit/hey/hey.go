@@ -0,0 +1,280 @@
+// Package hey parses the text report printed by the hey HTTP load generator
+// (github.com/rakyll/hey) so archived runs can be compared with results
+// produced by the lessons' own tooling.
+package hey
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Bucket is one row of the response time histogram: Count responses took
+// at most Mark (and more than the previous bucket's Mark).
+type Bucket struct {
+	Mark  time.Duration `json:"mark_ns"`
+	Count int           `json:"count"`
+}
+
+// Percentile is one row of the latency distribution.
+type Percentile struct {
+	Percent float64       `json:"percent"`
+	Latency time.Duration `json:"latency_ns"`
+}
+
+// Detail is one row of the per-phase breakdown (DNS+dialup, req write, ...).
+type Detail struct {
+	Phase   string        `json:"phase"`
+	Average time.Duration `json:"average_ns"`
+	Fastest time.Duration `json:"fastest_ns"`
+	Slowest time.Duration `json:"slowest_ns"`
+}
+
+// Result is a parsed hey report. Sections missing from the input are left
+// empty; hey omits the error distribution when there were no errors.
+type Result struct {
+	Total          time.Duration  `json:"total_ns"`
+	Slowest        time.Duration  `json:"slowest_ns"`
+	Fastest        time.Duration  `json:"fastest_ns"`
+	Average        time.Duration  `json:"average_ns"`
+	RequestsPerSec float64        `json:"requests_per_sec"`
+	TotalData      int64          `json:"total_data_bytes"`
+	SizePerRequest int64          `json:"size_per_request_bytes"`
+	Histogram      []Bucket       `json:"histogram"`
+	Latencies      []Percentile   `json:"latencies"`
+	Details        []Detail       `json:"details"`
+	StatusCodes    map[int]int    `json:"status_codes"`
+	Errors         map[string]int `json:"errors,omitempty"`
+}
+
+// Requests is the number of responses hey counted, across all status codes.
+func (r *Result) Requests() int {
+	n := 0
+	for _, c := range r.StatusCodes {
+		n += c
+	}
+	return n
+}
+
+// Percentile returns the latency reported for p (e.g. 99), or false if the
+// report has no such row.
+func (r *Result) Percentile(p float64) (time.Duration, bool) {
+	for _, l := range r.Latencies {
+		if l.Percent == p {
+			return l.Latency, true
+		}
+	}
+	return 0, false
+}
+
+// ParseFile parses the hey report stored at path.
+func ParseFile(path string) (*Result, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	res, err := Parse(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return res, nil
+}
+
+// Parse reads a hey text report. It returns an error if the input has no
+// Summary section or a line in a known section cannot be parsed.
+func Parse(r io.Reader) (*Result, error) {
+	res := &Result{StatusCodes: map[int]int{}}
+	section := ""
+	sawSummary := false
+	sc := bufio.NewScanner(r)
+	for lineNo := 1; sc.Scan(); lineNo++ {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" {
+			continue
+		}
+		// Section headers are the only unindented lines.
+		if !strings.HasPrefix(sc.Text(), " ") {
+			section = line
+			sawSummary = sawSummary || section == "Summary:"
+			continue
+		}
+		var err error
+		switch {
+		case section == "Summary:":
+			err = parseSummary(res, line)
+		case section == "Response time histogram:":
+			err = parseBucket(res, line)
+		case section == "Latency distribution:":
+			err = parsePercentile(res, line)
+		case strings.HasPrefix(section, "Details"):
+			err = parseDetail(res, line)
+		case section == "Status code distribution:":
+			err = parseStatus(res, line)
+		case section == "Error distribution:":
+			err = parseError(res, line)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNo, err)
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	if !sawSummary {
+		return nil, fmt.Errorf("no Summary section; not a hey report")
+	}
+	return res, nil
+}
+
+// field splits "Name:\tvalue" into its trimmed parts.
+func field(line string) (string, string, bool) {
+	name, value, ok := strings.Cut(line, ":")
+	return strings.TrimSpace(name), strings.TrimSpace(value), ok
+}
+
+func parseSummary(res *Result, line string) error {
+	name, value, ok := field(line)
+	if !ok {
+		return fmt.Errorf("summary line %q", line)
+	}
+	var err error
+	switch name {
+	case "Total":
+		res.Total, err = parseSecs(value)
+	case "Slowest":
+		res.Slowest, err = parseSecs(value)
+	case "Fastest":
+		res.Fastest, err = parseSecs(value)
+	case "Average":
+		res.Average, err = parseSecs(value)
+	case "Requests/sec":
+		res.RequestsPerSec, err = strconv.ParseFloat(value, 64)
+	case "Total data":
+		res.TotalData, err = parseBytes(value)
+	case "Size/request":
+		res.SizePerRequest, err = parseBytes(value)
+	}
+	return err
+}
+
+// parseBucket reads "  0.001 [377167]\t|■■■■".
+func parseBucket(res *Result, line string) error {
+	mark, rest, ok := strings.Cut(line, " [")
+	count, _, ok2 := strings.Cut(rest, "]")
+	if !ok || !ok2 {
+		return fmt.Errorf("histogram line %q", line)
+	}
+	d, err := parseSecs(mark)
+	if err != nil {
+		return err
+	}
+	n, err := strconv.Atoi(count)
+	if err != nil {
+		return fmt.Errorf("histogram count %q", count)
+	}
+	res.Histogram = append(res.Histogram, Bucket{Mark: d, Count: n})
+	return nil
+}
+
+// parsePercentile reads "  99%% in 0.0025 secs". hey prints the doubled %%
+// literally; a single % is accepted too.
+func parsePercentile(res *Result, line string) error {
+	pct, latency, ok := strings.Cut(line, " in ")
+	if !ok {
+		return fmt.Errorf("latency line %q", line)
+	}
+	p, err := strconv.ParseFloat(strings.TrimRight(pct, "%"), 64)
+	if err != nil {
+		return fmt.Errorf("latency percent %q", pct)
+	}
+	d, err := parseSecs(latency)
+	if err != nil {
+		return err
+	}
+	res.Latencies = append(res.Latencies, Percentile{Percent: p, Latency: d})
+	return nil
+}
+
+// parseDetail reads "  resp wait:\t0.0005 secs, 0.0000 secs, 0.0072 secs".
+func parseDetail(res *Result, line string) error {
+	name, value, ok := field(line)
+	parts := strings.Split(value, ",")
+	if !ok || len(parts) != 3 {
+		return fmt.Errorf("details line %q", line)
+	}
+	var ds [3]time.Duration
+	for i, p := range parts {
+		d, err := parseSecs(p)
+		if err != nil {
+			return err
+		}
+		ds[i] = d
+	}
+	res.Details = append(res.Details, Detail{Phase: name, Average: ds[0], Fastest: ds[1], Slowest: ds[2]})
+	return nil
+}
+
+// parseStatus reads "  [200]\t500000 responses".
+func parseStatus(res *Result, line string) error {
+	code, count, ok := bracketed(line)
+	if !ok {
+		return fmt.Errorf("status line %q", line)
+	}
+	c, err := strconv.Atoi(code)
+	if err != nil {
+		return fmt.Errorf("status code %q", code)
+	}
+	n, err := strconv.Atoi(strings.TrimSuffix(count, " responses"))
+	if err != nil {
+		return fmt.Errorf("status count %q", count)
+	}
+	res.StatusCodes[c] += n
+	return nil
+}
+
+// parseError reads "  [12]\tGet http://...: connection refused".
+func parseError(res *Result, line string) error {
+	count, msg, ok := bracketed(line)
+	n, err := strconv.Atoi(count)
+	if !ok || err != nil {
+		return fmt.Errorf("error line %q", line)
+	}
+	if res.Errors == nil {
+		res.Errors = map[string]int{}
+	}
+	res.Errors[msg] += n
+	return nil
+}
+
+// bracketed splits "[inner] rest".
+func bracketed(line string) (string, string, bool) {
+	if !strings.HasPrefix(line, "[") {
+		return "", "", false
+	}
+	inner, rest, ok := strings.Cut(line[1:], "]")
+	return inner, strings.TrimSpace(rest), ok
+}
+
+// parseSecs reads hey's "0.0072 secs" (the unit is optional).
+func parseSecs(s string) (time.Duration, error) {
+	s = strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(s), "secs"))
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, fmt.Errorf("duration %q", s)
+	}
+	return time.Duration(math.Round(f * float64(time.Second))), nil
+}
+
+func parseBytes(s string) (int64, error) {
+	n, err := strconv.ParseInt(strings.TrimSpace(strings.TrimSuffix(s, "bytes")), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("byte count %q", s)
+	}
+	return n, nil
+}
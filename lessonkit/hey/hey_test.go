@@ -0,0 +1,84 @@
+package hey
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// sample is hey's report format as written by the lessons' archived runs,
+// including the literal "%%" hey prints, plus an error section.
+const sample = `
+Summary:
+  Total:	4.7798 secs
+  Slowest:	0.0131 secs
+  Fastest:	0.0000 secs
+  Average:	0.0005 secs
+  Requests/sec:	104606.0176
+  
+  Total data:	62449118 bytes
+  Size/request:	124 bytes
+
+Response time histogram:
+  0.000 [1]	|
+  0.001 [465191]	|■■■■■■■■■■■■■■■■■■■■■■■■■■■■■■■■■■■■■■■■
+  0.003 [31424]	|■■■
+
+
+Latency distribution:
+  10%% in 0.0001 secs
+  50%% in 0.0003 secs
+  99%% in 0.0024 secs
+
+Details (average, fastest, slowest):
+  DNS+dialup:	0.0000 secs, 0.0000 secs, 0.0008 secs
+  resp wait:	0.0004 secs, 0.0000 secs, 0.0087 secs
+
+Status code distribution:
+  [200]	496600 responses
+  [503]	16 responses
+
+Error distribution:
+  [3]	Get "http://localhost:8080/pooled?size=4096": dial tcp: connection refused
+`
+
+func TestParse(t *testing.T) {
+	res, err := Parse(strings.NewReader(sample))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Total != 4779800*time.Microsecond || res.Slowest != 13100*time.Microsecond || res.Average != 500*time.Microsecond {
+		t.Errorf("summary durations = %v %v %v", res.Total, res.Slowest, res.Average)
+	}
+	if res.RequestsPerSec != 104606.0176 || res.TotalData != 62449118 || res.SizePerRequest != 124 {
+		t.Errorf("summary = %+v", res)
+	}
+	if len(res.Histogram) != 3 || res.Histogram[1] != (Bucket{Mark: time.Millisecond, Count: 465191}) {
+		t.Errorf("histogram = %+v", res.Histogram)
+	}
+	if p99, ok := res.Percentile(99); !ok || p99 != 2400*time.Microsecond || len(res.Latencies) != 3 {
+		t.Errorf("latencies = %+v", res.Latencies)
+	}
+	if len(res.Details) != 2 || res.Details[1] != (Detail{Phase: "resp wait", Average: 400 * time.Microsecond, Slowest: 8700 * time.Microsecond}) {
+		t.Errorf("details = %+v", res.Details)
+	}
+	if res.StatusCodes[200] != 496600 || res.StatusCodes[503] != 16 || res.Requests() != 496616 {
+		t.Errorf("status codes = %v", res.StatusCodes)
+	}
+	if len(res.Errors) != 1 || res.Errors[`Get "http://localhost:8080/pooled?size=4096": dial tcp: connection refused`] != 3 {
+		t.Errorf("errors = %v", res.Errors)
+	}
+}
+
+func TestParseRejectsBadInput(t *testing.T) {
+	for name, in := range map[string]string{
+		"not hey":       "hello world\n",
+		"bad summary":   "Summary:\n  Total:\tfast secs\n",
+		"bad histogram": "Summary:\nResponse time histogram:\n  0.001 [x]\t|\n",
+		"bad status":    "Summary:\nStatus code distribution:\n  200 responses\n",
+	} {
+		if _, err := Parse(strings.NewReader(in)); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
@@ -0,0 +1,212 @@
+// Package layout reports struct field offsets, padding and the smallest
+// field ordering, either from reflect types or from static size information.
+package layout
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+)
+
+// Field describes one field's placement within its struct.
+type Field struct {
+	Name    string  `json:"name"`
+	Type    string  `json:"type"`
+	Offset  uintptr `json:"offset"`
+	Size    uintptr `json:"size"`
+	Align   uintptr `json:"align"`
+	Padding uintptr `json:"padding"` // bytes inserted after this field
+}
+
+// Report is the layout analysis of one struct type.
+type Report struct {
+	Name         string   `json:"name"`
+	Size         uintptr  `json:"size"`
+	Align        uintptr  `json:"align"`
+	Padding      uintptr  `json:"padding"`
+	Fields       []Field  `json:"fields"`
+	OptimalSize  uintptr  `json:"optimal_size"`
+	OptimalOrder []string `json:"optimal_order"`
+}
+
+// Wasted returns how many bytes the optimal ordering would save.
+func (r Report) Wasted() uintptr {
+	return r.Size - r.OptimalSize
+}
+
+// Spec is the size and alignment of a field, independent of where the
+// information came from (reflect at runtime or go/types statically).
+type Spec struct {
+	Name  string
+	Type  string
+	Size  uintptr
+	Align uintptr
+}
+
+// Analyze reports the layout of struct type t as the compiler laid it out.
+func Analyze(t reflect.Type) (Report, error) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return Report{}, fmt.Errorf("layout: %s is not a struct", t)
+	}
+	specs := make([]Spec, t.NumField())
+	offsets := make([]uintptr, t.NumField())
+	for i := range specs {
+		f := t.Field(i)
+		specs[i] = Spec{Name: f.Name, Type: f.Type.String(), Size: f.Type.Size(), Align: uintptr(f.Type.Align())}
+		offsets[i] = f.Offset
+	}
+	return build(t.String(), t.Size(), uintptr(t.Align()), specs, offsets), nil
+}
+
+// AnalyzeSpecs lays out specs in declaration order using the gc rules and
+// reports the result. It is used when no reflect.Type is available.
+func AnalyzeSpecs(name string, specs []Spec) Report {
+	size, align, offsets := Place(specs)
+	return build(name, size, align, specs, offsets)
+}
+
+func build(name string, size, align uintptr, specs []Spec, offsets []uintptr) Report {
+	r := Report{Name: name, Size: size, Align: align}
+	var used uintptr
+	for i, s := range specs {
+		next := size
+		if i+1 < len(specs) {
+			next = offsets[i+1]
+		}
+		r.Fields = append(r.Fields, Field{
+			Name:    s.Name,
+			Type:    s.Type,
+			Offset:  offsets[i],
+			Size:    s.Size,
+			Align:   s.Align,
+			Padding: next - offsets[i] - s.Size,
+		})
+		used += s.Size
+	}
+	r.Padding = size - used
+
+	optimal := Optimal(specs)
+	r.OptimalSize, _, _ = Place(optimal)
+	if r.OptimalSize >= r.Size {
+		// Never suggest an ordering that is not strictly better.
+		r.OptimalSize = r.Size
+		optimal = specs
+	}
+	for _, s := range optimal {
+		r.OptimalOrder = append(r.OptimalOrder, s.Name)
+	}
+	return r
+}
+
+// Optimal returns specs reordered to minimize padding: zero-sized fields
+// first, then by decreasing alignment and size. Ties keep declaration order.
+func Optimal(specs []Spec) []Spec {
+	out := append([]Spec(nil), specs...)
+	sort.SliceStable(out, func(i, j int) bool {
+		a, b := out[i], out[j]
+		if (a.Size == 0) != (b.Size == 0) {
+			return a.Size == 0
+		}
+		if a.Align != b.Align {
+			return a.Align > b.Align
+		}
+		return a.Size > b.Size
+	})
+	return out
+}
+
+// Place computes field offsets, total size and alignment for specs laid out
+// in order, following the gc compiler's struct layout rules.
+func Place(specs []Spec) (size, align uintptr, offsets []uintptr) {
+	align = 1
+	var off uintptr
+	offsets = make([]uintptr, len(specs))
+	for i, s := range specs {
+		a := s.Align
+		if a == 0 {
+			a = 1
+		}
+		if a > align {
+			align = a
+		}
+		off = roundUp(off, a)
+		offsets[i] = off
+		off += s.Size
+	}
+	// A trailing zero-size field would point past the struct; gc pads it.
+	if n := len(specs); n > 0 && specs[n-1].Size == 0 && off > 0 {
+		off++
+	}
+	return roundUp(off, align), align, offsets
+}
+
+func roundUp(n, a uintptr) uintptr {
+	return (n + a - 1) &^ (a - 1)
+}
+
+// Registry holds named struct types whose layout can be served on request.
+type Registry struct {
+	mu    sync.RWMutex
+	types map[string]reflect.Type
+	order []string
+}
+
+// NewRegistry returns an empty registry.
+func NewRegistry() *Registry {
+	return &Registry{types: make(map[string]reflect.Type)}
+}
+
+// Register adds the struct type of v (a value or pointer) under name.
+func (r *Registry) Register(name string, v any) error {
+	t := reflect.TypeOf(v)
+	if t == nil {
+		return fmt.Errorf("layout: cannot register nil")
+	}
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return fmt.Errorf("layout: %s is not a struct", t)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.types[name]; !ok {
+		r.order = append(r.order, name)
+	}
+	r.types[name] = t
+	return nil
+}
+
+// Report analyzes the type registered under name.
+func (r *Registry) Report(name string) (Report, bool) {
+	r.mu.RLock()
+	t, ok := r.types[name]
+	r.mu.RUnlock()
+	if !ok {
+		return Report{}, false
+	}
+	rep, err := Analyze(t)
+	if err != nil {
+		return Report{}, false
+	}
+	rep.Name = name
+	return rep, true
+}
+
+// Reports analyzes every registered type in registration order.
+func (r *Registry) Reports() []Report {
+	r.mu.RLock()
+	names := append([]string(nil), r.order...)
+	r.mu.RUnlock()
+	out := make([]Report, 0, len(names))
+	for _, n := range names {
+		if rep, ok := r.Report(n); ok {
+			out = append(out, rep)
+		}
+	}
+	return out
+}
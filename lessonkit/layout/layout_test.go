@@ -0,0 +1,70 @@
+package layout
+
+import (
+	"reflect"
+	"testing"
+)
+
+type padded struct {
+	A bool
+	B int64
+	C bool
+	D int32
+}
+
+func TestAnalyze(t *testing.T) {
+	r, err := Analyze(reflect.TypeOf(padded{}))
+	if err != nil {
+		t.Fatalf("Analyze: %v", err)
+	}
+	if r.Size != 24 || r.Align != 8 {
+		t.Errorf("size/align = %d/%d, want 24/8", r.Size, r.Align)
+	}
+	wantOffsets := []uintptr{0, 8, 16, 20}
+	wantPadding := []uintptr{7, 0, 3, 0}
+	for i, f := range r.Fields {
+		if f.Offset != wantOffsets[i] || f.Padding != wantPadding[i] {
+			t.Errorf("field %s offset/padding = %d/%d, want %d/%d", f.Name, f.Offset, f.Padding, wantOffsets[i], wantPadding[i])
+		}
+	}
+	if r.Padding != 10 {
+		t.Errorf("Padding = %d, want 10", r.Padding)
+	}
+	if r.OptimalSize != 16 || r.Wasted() != 8 {
+		t.Errorf("OptimalSize = %d, Wasted = %d, want 16/8", r.OptimalSize, r.Wasted())
+	}
+	if got := r.OptimalOrder; !reflect.DeepEqual(got, []string{"B", "D", "A", "C"}) {
+		t.Errorf("OptimalOrder = %v", got)
+	}
+}
+
+func TestAnalyzeSpecsMatchesCompiler(t *testing.T) {
+	typ := reflect.TypeOf(padded{})
+	specs := make([]Spec, typ.NumField())
+	for i := range specs {
+		f := typ.Field(i)
+		specs[i] = Spec{Name: f.Name, Size: f.Type.Size(), Align: uintptr(f.Type.Align())}
+	}
+	static := AnalyzeSpecs("padded", specs)
+	dynamic, _ := Analyze(typ)
+	if static.Size != dynamic.Size || static.OptimalSize != dynamic.OptimalSize {
+		t.Errorf("static %d/%d != reflect %d/%d", static.Size, static.OptimalSize, dynamic.Size, dynamic.OptimalSize)
+	}
+}
+
+func TestRegistry(t *testing.T) {
+	r := NewRegistry()
+	if err := r.Register("padded", &padded{}); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	if err := r.Register("int", 3); err == nil {
+		t.Error("registering a non-struct should fail")
+	}
+	reps := r.Reports()
+	if len(reps) != 1 || reps[0].Name != "padded" {
+		t.Errorf("Reports = %+v", reps)
+	}
+	if _, ok := r.Report("missing"); ok {
+		t.Error("Report on unknown name reported ok")
+	}
+}
@@ -0,0 +1,84 @@
+package logging
+
+import (
+	"io"
+	"sync"
+	"sync/atomic"
+)
+
+// AsyncWriter queues writes for a background goroutine. When the queue is
+// full, lines are dropped and counted rather than blocking the caller:
+// a slow log sink must never add latency to request handling.
+type AsyncWriter struct {
+	out     io.Writer
+	lines   chan *[]byte
+	done    chan struct{}
+	dropped atomic.Uint64
+
+	mu     sync.RWMutex // guards closed against concurrent Write/Close
+	closed bool
+}
+
+var lineBufs = sync.Pool{
+	New: func() interface{} {
+		b := make([]byte, 0, 256)
+		return &b
+	},
+}
+
+// NewAsyncWriter starts a writer that forwards to out with room for size queued lines.
+func NewAsyncWriter(out io.Writer, size int) *AsyncWriter {
+	if size <= 0 {
+		size = 1024
+	}
+	a := &AsyncWriter{out: out, lines: make(chan *[]byte, size), done: make(chan struct{})}
+	go a.run()
+	return a
+}
+
+func (a *AsyncWriter) run() {
+	defer close(a.done)
+	for b := range a.lines {
+		a.out.Write(*b)
+		*b = (*b)[:0]
+		lineBufs.Put(b)
+	}
+}
+
+// Write copies p onto the queue. It never blocks; if the queue is full or the
+// writer is closed the line is dropped. It always reports success so slog
+// handlers do not treat back-pressure as an error.
+func (a *AsyncWriter) Write(p []byte) (int, error) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	if a.closed {
+		a.dropped.Add(1)
+		return len(p), nil
+	}
+	b := lineBufs.Get().(*[]byte)
+	*b = append((*b)[:0], p...)
+	select {
+	case a.lines <- b:
+	default:
+		a.dropped.Add(1)
+		lineBufs.Put(b)
+	}
+	return len(p), nil
+}
+
+// Dropped returns how many lines were discarded because the queue was full.
+func (a *AsyncWriter) Dropped() uint64 {
+	return a.dropped.Load()
+}
+
+// Close stops accepting lines and waits until queued lines are written.
+func (a *AsyncWriter) Close() error {
+	a.mu.Lock()
+	if !a.closed {
+		a.closed = true
+		close(a.lines)
+	}
+	a.mu.Unlock()
+	<-a.done
+	return nil
+}
@@ -0,0 +1,128 @@
+// Package logging builds log/slog loggers for the lesson servers: leveled,
+// structured output with request IDs, optional 1-in-N sampling and an
+// asynchronous buffered sink so logging stays off the request hot path.
+package logging
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+	"sync/atomic"
+)
+
+// Options configures New.
+type Options struct {
+	Level slog.Level
+	// SampleEvery keeps one in N records below slog.LevelWarn. 0 or 1 keeps all.
+	SampleEvery uint64
+	// Async moves writes to a background goroutine with BufferSize queued lines.
+	Async      bool
+	BufferSize int
+	JSON       bool
+}
+
+// DefaultOptions logs everything at Info and above as JSON through an async sink.
+func DefaultOptions() Options {
+	return Options{Level: slog.LevelInfo, SampleEvery: 1, Async: true, BufferSize: 4096, JSON: true}
+}
+
+// OptionsFromEnv starts from DefaultOptions and applies LOG_LEVEL
+// (debug|info|warn|error), LOG_SAMPLE (N), LOG_ASYNC (true|false) and
+// LOG_FORMAT (json|text).
+func OptionsFromEnv() Options {
+	opts := DefaultOptions()
+	if s := os.Getenv("LOG_LEVEL"); s != "" {
+		var lvl slog.Level
+		if err := lvl.UnmarshalText([]byte(s)); err == nil {
+			opts.Level = lvl
+		}
+	}
+	if n, err := strconv.ParseUint(os.Getenv("LOG_SAMPLE"), 10, 64); err == nil {
+		opts.SampleEvery = n
+	}
+	if b, err := strconv.ParseBool(os.Getenv("LOG_ASYNC")); err == nil {
+		opts.Async = b
+	}
+	if strings.EqualFold(os.Getenv("LOG_FORMAT"), "text") {
+		opts.JSON = false
+	}
+	return opts
+}
+
+// New returns a logger writing to w and a func that flushes and stops any
+// async sink. The close func is safe to call when Async is false.
+func New(w io.Writer, opts Options) (*slog.Logger, func() error) {
+	closeFn := func() error { return nil }
+	if opts.Async {
+		aw := NewAsyncWriter(w, opts.BufferSize)
+		w, closeFn = aw, aw.Close
+	}
+	hopts := &slog.HandlerOptions{Level: opts.Level}
+	var h slog.Handler
+	if opts.JSON {
+		h = slog.NewJSONHandler(w, hopts)
+	} else {
+		h = slog.NewTextHandler(w, hopts)
+	}
+	h = contextHandler{h}
+	if opts.SampleEvery > 1 {
+		h = NewSamplingHandler(h, opts.SampleEvery, slog.LevelWarn)
+	}
+	return slog.New(h), closeFn
+}
+
+// contextHandler adds the request ID stored in the context to every record.
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h contextHandler) Handle(ctx context.Context, r slog.Record) error {
+	if id := RequestID(ctx); id != "" {
+		r.AddAttrs(slog.String("request_id", id))
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.Handler.WithGroup(name)}
+}
+
+// SamplingHandler passes one in every N records below a threshold level and
+// all records at or above it, so errors are never sampled away.
+type SamplingHandler struct {
+	next   slog.Handler
+	every  uint64
+	always slog.Level
+	seen   *atomic.Uint64 // shared by handlers derived via WithAttrs/WithGroup
+}
+
+// NewSamplingHandler wraps next, keeping one in every records below always.
+func NewSamplingHandler(next slog.Handler, every uint64, always slog.Level) *SamplingHandler {
+	return &SamplingHandler{next: next, every: every, always: always, seen: new(atomic.Uint64)}
+}
+
+func (h *SamplingHandler) Enabled(ctx context.Context, lvl slog.Level) bool {
+	return h.next.Enabled(ctx, lvl)
+}
+
+func (h *SamplingHandler) Handle(ctx context.Context, r slog.Record) error {
+	if r.Level < h.always && h.every > 1 && h.seen.Add(1)%h.every != 1 {
+		return nil
+	}
+	return h.next.Handle(ctx, r)
+}
+
+func (h *SamplingHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &SamplingHandler{next: h.next.WithAttrs(attrs), every: h.every, always: h.always, seen: h.seen}
+}
+
+func (h *SamplingHandler) WithGroup(name string) slog.Handler {
+	return &SamplingHandler{next: h.next.WithGroup(name), every: h.every, always: h.always, seen: h.seen}
+}
@@ -0,0 +1,158 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// syncBuffer is a bytes.Buffer safe for the async writer goroutine.
+type syncBuffer struct {
+	mu sync.Mutex
+	b  bytes.Buffer
+}
+
+func (s *syncBuffer) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.b.Write(p)
+}
+
+func (s *syncBuffer) String() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.b.String()
+}
+
+func TestSamplingKeepsWarnings(t *testing.T) {
+	var buf syncBuffer
+	logger, closeFn := New(&buf, Options{Level: slog.LevelInfo, SampleEvery: 10, JSON: true})
+	for i := 0; i < 100; i++ {
+		logger.Info("tick")
+	}
+	logger.Warn("careful")
+	closeFn()
+
+	out := buf.String()
+	if n := strings.Count(out, `"msg":"tick"`); n != 10 {
+		t.Errorf("sampled info lines = %d, want 10", n)
+	}
+	if !strings.Contains(out, `"msg":"careful"`) {
+		t.Error("warning was sampled away")
+	}
+}
+
+func TestAsyncWriterFlushesOnClose(t *testing.T) {
+	var buf syncBuffer
+	logger, closeFn := New(&buf, Options{Level: slog.LevelDebug, Async: true, BufferSize: 1000, JSON: false})
+	for i := 0; i < 500; i++ {
+		logger.Debug("line", "i", i)
+	}
+	closeFn()
+	if n := strings.Count(buf.String(), "msg=line"); n != 500 {
+		t.Errorf("flushed lines = %d, want 500", n)
+	}
+}
+
+func TestAsyncWriterDropsWhenFull(t *testing.T) {
+	block := make(chan struct{})
+	aw := NewAsyncWriter(writerFunc(func(p []byte) (int, error) {
+		<-block
+		return len(p), nil
+	}), 1)
+	for i := 0; i < 10; i++ {
+		aw.Write([]byte("x\n"))
+	}
+	close(block)
+	aw.Close()
+	if aw.Dropped() == 0 {
+		t.Error("expected dropped lines with a blocked sink")
+	}
+}
+
+type writerFunc func([]byte) (int, error)
+
+func (f writerFunc) Write(p []byte) (int, error) { return f(p) }
+
+func TestMiddlewareRequestID(t *testing.T) {
+	var buf syncBuffer
+	logger, closeFn := New(&buf, Options{Level: slog.LevelInfo, JSON: true})
+	var seen string
+	h := Middleware(logger, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = RequestID(r.Context())
+		logger.InfoContext(r.Context(), "inside")
+		w.WriteHeader(http.StatusTeapot)
+	}))
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/x", nil))
+	if seen == "" || w.Header().Get(RequestIDHeader) != seen {
+		t.Errorf("request ID = %q, header = %q", seen, w.Header().Get(RequestIDHeader))
+	}
+
+	req := httptest.NewRequest("GET", "/y", nil)
+	req.Header.Set(RequestIDHeader, "upstream-1")
+	h.ServeHTTP(httptest.NewRecorder(), req)
+
+	// Oversized or odd IDs are replaced, never echoed or logged.
+	for _, bad := range []string{strings.Repeat("a", MaxRequestIDLen+1), "evil\"}\n", "a b"} {
+		req = httptest.NewRequest("GET", "/z", nil)
+		req.Header.Set(RequestIDHeader, bad)
+		w = httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		if got := w.Header().Get(RequestIDHeader); got == bad || !ValidRequestID(got) {
+			t.Errorf("X-Request-ID %q answered with %q", bad, got)
+		}
+	}
+	closeFn()
+
+	out := buf.String()
+	for _, want := range []string{`"msg":"inside","request_id":"` + seen, `"status":418`, `"request_id":"upstream-1"`} {
+		if !strings.Contains(out, want) {
+			t.Errorf("log output missing %q:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "evil") {
+		t.Errorf("log output contains a rejected request ID:\n%s", out)
+	}
+	if a, b := NewRequestID(), NewRequestID(); a == b {
+		t.Errorf("NewRequestID repeated %q", a)
+	}
+}
+
+// BenchmarkRequestLogging shows the per-request cost of one structured log
+// line under each configuration, with many goroutines logging at once.
+func BenchmarkRequestLogging(b *testing.B) {
+	cases := []struct {
+		name string
+		opts Options
+	}{
+		{"disabled", Options{Level: slog.LevelWarn, JSON: true}},
+		{"sampled-1in100", Options{Level: slog.LevelInfo, SampleEvery: 100, JSON: true}},
+		{"sync", Options{Level: slog.LevelInfo, JSON: true}},
+		{"async", Options{Level: slog.LevelInfo, Async: true, BufferSize: 4096, JSON: true}},
+	}
+	for _, c := range cases {
+		b.Run(c.name, func(b *testing.B) {
+			logger, closeFn := New(io.Discard, c.opts)
+			defer closeFn()
+			ctx := WithRequestID(context.Background(), "bench-00000001")
+			b.ReportAllocs()
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					logger.LogAttrs(ctx, slog.LevelInfo, "request",
+						slog.String("method", "GET"),
+						slog.String("path", "/stats-heap"),
+						slog.Int("status", 200),
+					)
+				}
+			})
+		})
+	}
+}
@@ -0,0 +1,147 @@
+package logging
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+// RequestIDHeader is read from incoming requests and echoed on responses.
+const RequestIDHeader = "X-Request-ID"
+
+// MaxRequestIDLen caps incoming request IDs, which end up in every log line
+// and response of the request.
+const MaxRequestIDLen = 64
+
+type requestIDKey struct{}
+
+// WithRequestID returns a context carrying id.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestID returns the request ID stored in ctx, or "".
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+var (
+	// idPrefix makes IDs unique across restarts; idSeq makes them unique within a process.
+	idPrefix = randomHex(4)
+	idSeq    atomic.Uint64
+)
+
+// NewRequestID returns a process-unique ID such as "9f86d081-0000002a".
+func NewRequestID() string {
+	return fmt.Sprintf("%s-%08x", idPrefix, idSeq.Add(1))
+}
+
+// ValidRequestID reports whether id may be echoed and logged: 1 to
+// MaxRequestIDLen letters, digits and "-", "_", ".", ":".
+func ValidRequestID(id string) bool {
+	if id == "" || len(id) > MaxRequestIDLen {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		switch c := id[i]; {
+		case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9':
+		case c == '-', c == '_', c == '.', c == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// IncomingRequestID returns the client's X-Request-ID, or "" if it is
+// missing or fails ValidRequestID, so the caller generates one instead.
+func IncomingRequestID(r *http.Request) string {
+	if id := r.Header.Get(RequestIDHeader); ValidRequestID(id) {
+		return id
+	}
+	return ""
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "00000000"[:2*n]
+	}
+	return hex.EncodeToString(b)
+}
+
+// StatusRecorder captures the status code and body size written by a handler.
+type StatusRecorder struct {
+	http.ResponseWriter
+	Status int
+	Bytes  int
+}
+
+// WriteHeader records the status before forwarding it.
+func (s *StatusRecorder) WriteHeader(code int) {
+	if s.Status == 0 {
+		s.Status = code
+	}
+	s.ResponseWriter.WriteHeader(code)
+}
+
+// Write records an implicit 200 and the number of bytes written.
+func (s *StatusRecorder) Write(p []byte) (int, error) {
+	if s.Status == 0 {
+		s.Status = http.StatusOK
+	}
+	n, err := s.ResponseWriter.Write(p)
+	s.Bytes += n
+	return n, err
+}
+
+// Flush forwards to the underlying writer so streaming handlers keep working.
+func (s *StatusRecorder) Flush() {
+	if f, ok := s.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (s *StatusRecorder) Unwrap() http.ResponseWriter {
+	return s.ResponseWriter
+}
+
+// Middleware assigns each request an ID (keeping one already in the context,
+// e.g. from tracing.Middleware, or honouring a valid incoming X-Request-ID),
+// stores it in the request context for handlers' log calls, and logs one
+// structured line per completed request.
+func Middleware(logger *slog.Logger, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		id := RequestID(r.Context())
+		if id == "" {
+			id = IncomingRequestID(r)
+		}
+		if id == "" {
+			id = NewRequestID()
+		}
+		ctx := WithRequestID(r.Context(), id)
+		w.Header().Set(RequestIDHeader, id)
+
+		rec := &StatusRecorder{ResponseWriter: w}
+		next.ServeHTTP(rec, r.WithContext(ctx))
+		if rec.Status == 0 {
+			rec.Status = http.StatusOK
+		}
+
+		logger.LogAttrs(ctx, slog.LevelInfo, "request",
+			slog.String("method", r.Method),
+			slog.String("path", r.URL.Path),
+			slog.Int("status", rec.Status),
+			slog.Int("bytes", rec.Bytes),
+			slog.Duration("duration", time.Since(start)),
+		)
+	})
+}
@@ -0,0 +1,387 @@
+// Package metrics is a small Prometheus-compatible metrics registry shared by
+// the lesson servers. It renders the text exposition format without external deps.
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// collector is anything the registry can render as one metric family.
+type collector interface {
+	name() string
+	write(w io.Writer) error
+}
+
+// Registry holds metric families and renders them at /metrics.
+type Registry struct {
+	mu         sync.RWMutex
+	collectors []collector
+	names      map[string]bool
+}
+
+// NewRegistry returns an empty registry.
+func NewRegistry() *Registry {
+	return &Registry{names: make(map[string]bool)}
+}
+
+func (r *Registry) register(c collector) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.names[c.name()] {
+		panic(fmt.Sprintf("metrics: duplicate metric %q", c.name()))
+	}
+	r.names[c.name()] = true
+	r.collectors = append(r.collectors, c)
+}
+
+// WriteText renders every registered family in the Prometheus text format.
+func (r *Registry) WriteText(w io.Writer) error {
+	r.mu.RLock()
+	cs := append([]collector(nil), r.collectors...)
+	r.mu.RUnlock()
+	sort.Slice(cs, func(i, j int) bool { return cs[i].name() < cs[j].name() })
+	for _, c := range cs {
+		if err := c.write(w); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Handler serves the registry in the Prometheus text exposition format.
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		r.WriteText(w)
+	})
+}
+
+// atomicFloat is a float64 updated with compare-and-swap.
+type atomicFloat struct {
+	bits atomic.Uint64
+}
+
+func (f *atomicFloat) add(v float64) {
+	for {
+		old := f.bits.Load()
+		if f.bits.CompareAndSwap(old, math.Float64bits(math.Float64frombits(old)+v)) {
+			return
+		}
+	}
+}
+
+func (f *atomicFloat) set(v float64) { f.bits.Store(math.Float64bits(v)) }
+func (f *atomicFloat) load() float64 { return math.Float64frombits(f.bits.Load()) }
+
+// family tracks the labelled children of one metric.
+type family[T any] struct {
+	metricName string
+	help       string
+	labelNames []string
+	newChild   func() *T
+
+	mu       sync.RWMutex
+	children map[string]*T
+	labels   map[string]string
+}
+
+func newFamily[T any](name, help string, labelNames []string, newChild func() *T) *family[T] {
+	return &family[T]{
+		metricName: name,
+		help:       help,
+		labelNames: labelNames,
+		newChild:   newChild,
+		children:   make(map[string]*T),
+		labels:     make(map[string]string),
+	}
+}
+
+func (f *family[T]) name() string { return f.metricName }
+
+// with returns the child for the given label values, creating it on first use.
+func (f *family[T]) with(values []string) *T {
+	if len(values) != len(f.labelNames) {
+		panic(fmt.Sprintf("metrics: %s expects %d label values, got %d", f.metricName, len(f.labelNames), len(values)))
+	}
+	key := strings.Join(values, "\xff")
+	f.mu.RLock()
+	c, ok := f.children[key]
+	f.mu.RUnlock()
+	if ok {
+		return c
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if c, ok = f.children[key]; ok {
+		return c
+	}
+	c = f.newChild()
+	f.children[key] = c
+	f.labels[key] = formatLabels(f.labelNames, values)
+	return c
+}
+
+// each calls fn for every child in label order.
+func (f *family[T]) each(fn func(labels string, c *T) error) error {
+	f.mu.RLock()
+	keys := make([]string, 0, len(f.children))
+	for k := range f.children {
+		keys = append(keys, k)
+	}
+	f.mu.RUnlock()
+	sort.Strings(keys)
+	for _, k := range keys {
+		f.mu.RLock()
+		c, labels := f.children[k], f.labels[k]
+		f.mu.RUnlock()
+		if err := fn(labels, c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (f *family[T]) writeHeader(w io.Writer, typ string) error {
+	_, err := fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", f.metricName, escapeHelp(f.help), f.metricName, typ)
+	return err
+}
+
+// CounterValue is one labelled series of a Counter.
+type CounterValue struct{ v atomicFloat }
+
+// Inc adds one.
+func (c *CounterValue) Inc() { c.v.add(1) }
+
+// Add adds v, which must not be negative.
+func (c *CounterValue) Add(v float64) {
+	if v < 0 {
+		panic("metrics: counter cannot decrease")
+	}
+	c.v.add(v)
+}
+
+// Counter is a monotonically increasing value, optionally partitioned by labels.
+type Counter struct{ f *family[CounterValue] }
+
+// NewCounter registers a counter. Label values are supplied later via With.
+func (r *Registry) NewCounter(name, help string, labelNames ...string) *Counter {
+	c := &Counter{f: newFamily(name, help, labelNames, func() *CounterValue { return &CounterValue{} })}
+	r.register(c)
+	return c
+}
+
+// With returns the series for the given label values.
+func (c *Counter) With(labelValues ...string) *CounterValue { return c.f.with(labelValues) }
+
+// Inc increments an unlabelled counter.
+func (c *Counter) Inc() { c.With().Inc() }
+
+// Add adds v to an unlabelled counter.
+func (c *Counter) Add(v float64) { c.With().Add(v) }
+
+func (c *Counter) name() string { return c.f.name() }
+
+func (c *Counter) write(w io.Writer) error {
+	if err := c.f.writeHeader(w, "counter"); err != nil {
+		return err
+	}
+	return c.f.each(func(labels string, v *CounterValue) error {
+		return writeSample(w, c.f.metricName, labels, v.v.load())
+	})
+}
+
+// GaugeValue is one labelled series of a Gauge.
+type GaugeValue struct{ v atomicFloat }
+
+// Set replaces the current value.
+func (g *GaugeValue) Set(v float64) { g.v.set(v) }
+
+// Add adds v, which may be negative.
+func (g *GaugeValue) Add(v float64) { g.v.add(v) }
+
+// Gauge is a value that can go up and down, optionally partitioned by labels.
+type Gauge struct{ f *family[GaugeValue] }
+
+// NewGauge registers a gauge.
+func (r *Registry) NewGauge(name, help string, labelNames ...string) *Gauge {
+	g := &Gauge{f: newFamily(name, help, labelNames, func() *GaugeValue { return &GaugeValue{} })}
+	r.register(g)
+	return g
+}
+
+// With returns the series for the given label values.
+func (g *Gauge) With(labelValues ...string) *GaugeValue { return g.f.with(labelValues) }
+
+// Set sets an unlabelled gauge.
+func (g *Gauge) Set(v float64) { g.With().Set(v) }
+
+// Add adds v to an unlabelled gauge.
+func (g *Gauge) Add(v float64) { g.With().Add(v) }
+
+func (g *Gauge) name() string { return g.f.name() }
+
+func (g *Gauge) write(w io.Writer) error {
+	if err := g.f.writeHeader(w, "gauge"); err != nil {
+		return err
+	}
+	return g.f.each(func(labels string, v *GaugeValue) error {
+		return writeSample(w, g.f.metricName, labels, v.v.load())
+	})
+}
+
+// funcMetric reports a value computed at scrape time.
+type funcMetric struct {
+	metricName, help, typ string
+	fn                    func() float64
+}
+
+// NewGaugeFunc registers a gauge whose value is computed by fn at scrape time.
+func (r *Registry) NewGaugeFunc(name, help string, fn func() float64) {
+	r.register(&funcMetric{metricName: name, help: help, typ: "gauge", fn: fn})
+}
+
+// NewCounterFunc registers a counter whose value is computed by fn at scrape time.
+func (r *Registry) NewCounterFunc(name, help string, fn func() float64) {
+	r.register(&funcMetric{metricName: name, help: help, typ: "counter", fn: fn})
+}
+
+func (m *funcMetric) name() string { return m.metricName }
+
+func (m *funcMetric) write(w io.Writer) error {
+	if _, err := fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", m.metricName, escapeHelp(m.help), m.metricName, m.typ); err != nil {
+		return err
+	}
+	return writeSample(w, m.metricName, "", m.fn())
+}
+
+// HistogramValue is one labelled series of a Histogram.
+type HistogramValue struct {
+	upper  []float64
+	counts []atomic.Uint64 // per bucket, not cumulative; last is +Inf
+	sum    atomicFloat
+	count  atomic.Uint64
+}
+
+// Observe records v.
+func (h *HistogramValue) Observe(v float64) {
+	i := sort.SearchFloat64s(h.upper, v)
+	h.counts[i].Add(1)
+	h.sum.add(v)
+	h.count.Add(1)
+}
+
+// Histogram counts observations into cumulative buckets.
+type Histogram struct {
+	f     *family[HistogramValue]
+	upper []float64
+}
+
+// DefBuckets are latency buckets in seconds from 100µs to 10s.
+var DefBuckets = []float64{.0001, .00025, .0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// NewHistogram registers a histogram with the given bucket upper bounds, which must be sorted.
+func (r *Registry) NewHistogram(name, help string, buckets []float64, labelNames ...string) *Histogram {
+	upper := append([]float64(nil), buckets...)
+	if !sort.Float64sAreSorted(upper) {
+		panic(fmt.Sprintf("metrics: %s buckets are not sorted", name))
+	}
+	h := &Histogram{upper: upper}
+	h.f = newFamily(name, help, labelNames, func() *HistogramValue {
+		return &HistogramValue{upper: upper, counts: make([]atomic.Uint64, len(upper)+1)}
+	})
+	r.register(h)
+	return h
+}
+
+// With returns the series for the given label values.
+func (h *Histogram) With(labelValues ...string) *HistogramValue { return h.f.with(labelValues) }
+
+// Observe records v in an unlabelled histogram.
+func (h *Histogram) Observe(v float64) { h.With().Observe(v) }
+
+func (h *Histogram) name() string { return h.f.name() }
+
+func (h *Histogram) write(w io.Writer) error {
+	if err := h.f.writeHeader(w, "histogram"); err != nil {
+		return err
+	}
+	return h.f.each(func(labels string, v *HistogramValue) error {
+		counts := make([]uint64, len(v.counts))
+		for i := range v.counts {
+			counts[i] = v.counts[i].Load()
+		}
+		return writeHistogram(w, h.f.metricName, labels, h.upper, counts, v.sum.load())
+	})
+}
+
+// writeHistogram renders per-bucket counts (last entry is +Inf) as cumulative buckets.
+func writeHistogram(w io.Writer, name, labels string, upper []float64, counts []uint64, sum float64) error {
+	var cum uint64
+	for i, ub := range upper {
+		cum += counts[i]
+		if err := writeSample(w, name+"_bucket", joinLabels(labels, `le="`+formatFloat(ub)+`"`), float64(cum)); err != nil {
+			return err
+		}
+	}
+	cum += counts[len(upper)]
+	if err := writeSample(w, name+"_bucket", joinLabels(labels, `le="+Inf"`), float64(cum)); err != nil {
+		return err
+	}
+	if err := writeSample(w, name+"_sum", labels, sum); err != nil {
+		return err
+	}
+	return writeSample(w, name+"_count", labels, float64(cum))
+}
+
+func writeSample(w io.Writer, name, labels string, v float64) error {
+	var err error
+	if labels == "" {
+		_, err = fmt.Fprintf(w, "%s %s\n", name, formatFloat(v))
+	} else {
+		_, err = fmt.Fprintf(w, "%s{%s} %s\n", name, labels, formatFloat(v))
+	}
+	return err
+}
+
+func formatLabels(names, values []string) string {
+	parts := make([]string, len(names))
+	for i := range names {
+		parts[i] = names[i] + `="` + escapeLabel(values[i]) + `"`
+	}
+	return strings.Join(parts, ",")
+}
+
+func joinLabels(a, b string) string {
+	if a == "" {
+		return b
+	}
+	return a + "," + b
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+var (
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	labelEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+)
+
+func escapeHelp(s string) string  { return helpEscaper.Replace(s) }
+func escapeLabel(s string) string { return labelEscaper.Replace(s) }
@@ -0,0 +1,102 @@
+package metrics
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func render(t *testing.T, r *Registry) string {
+	t.Helper()
+	var b strings.Builder
+	if err := r.WriteText(&b); err != nil {
+		t.Fatalf("WriteText: %v", err)
+	}
+	return b.String()
+}
+
+func TestCounterAndGauge(t *testing.T) {
+	r := NewRegistry()
+	c := r.NewCounter("jobs_total", "Jobs run.", "kind")
+	c.With("a").Inc()
+	c.With("a").Add(2)
+	c.With(`q"uote`).Inc()
+	g := r.NewGauge("queue_depth", "Items queued.")
+	g.Set(7)
+	g.Add(-2)
+	r.NewGaugeFunc("answer", "The answer.", func() float64 { return 42 })
+
+	out := render(t, r)
+	for _, want := range []string{
+		"# HELP jobs_total Jobs run.\n# TYPE jobs_total counter\n",
+		`jobs_total{kind="a"} 3`,
+		`jobs_total{kind="q\"uote"} 1`,
+		"# TYPE queue_depth gauge\nqueue_depth 5\n",
+		"answer 42\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q:\n%s", want, out)
+		}
+	}
+	if strings.Index(out, "answer") > strings.Index(out, "jobs_total") {
+		t.Error("families not sorted by name")
+	}
+}
+
+func TestHistogram(t *testing.T) {
+	r := NewRegistry()
+	h := r.NewHistogram("latency_seconds", "Latency.", []float64{0.1, 1})
+	h.Observe(0.05)
+	h.Observe(0.1)
+	h.Observe(0.5)
+	h.Observe(3)
+
+	out := render(t, r)
+	for _, want := range []string{
+		`latency_seconds_bucket{le="0.1"} 2`,
+		`latency_seconds_bucket{le="1"} 3`,
+		`latency_seconds_bucket{le="+Inf"} 4`,
+		"latency_seconds_sum 3.65\n",
+		"latency_seconds_count 4\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestDuplicateRegistrationPanics(t *testing.T) {
+	r := NewRegistry()
+	r.NewCounter("x_total", "x")
+	defer func() {
+		if recover() == nil {
+			t.Error("duplicate registration did not panic")
+		}
+	}()
+	r.NewGauge("x_total", "x")
+}
+
+func TestRuntimeMetricsAndHandler(t *testing.T) {
+	r := NewRegistry()
+	RegisterRuntimeMetrics(r)
+	NewRequestMetrics(r).Observe("naive", 2*time.Millisecond)
+
+	w := httptest.NewRecorder()
+	r.Handler().ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain; version=0.0.4") {
+		t.Errorf("Content-Type = %q", ct)
+	}
+	out := w.Body.String()
+	for _, want := range []string{
+		"# TYPE go_gc_heap_allocs_bytes_total counter",
+		"# TYPE go_gc_pauses_seconds histogram",
+		`go_gc_pauses_seconds_bucket{le="+Inf"}`,
+		`http_requests_total{handler="naive"} 1`,
+		`http_request_duration_seconds_bucket{handler="naive",le="0.0025"} 1`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q", want)
+		}
+	}
+}
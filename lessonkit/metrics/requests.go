@@ -0,0 +1,25 @@
+package metrics
+
+import "time"
+
+// RequestMetrics is the per-handler request instrumentation every lesson server
+// exposes, so dashboards and alerts can use the same series names everywhere.
+type RequestMetrics struct {
+	Requests *Counter
+	Duration *Histogram
+}
+
+// NewRequestMetrics registers http_requests_total and
+// http_request_duration_seconds, both labelled by handler.
+func NewRequestMetrics(r *Registry) *RequestMetrics {
+	return &RequestMetrics{
+		Requests: r.NewCounter("http_requests_total", "Requests handled, by handler.", "handler"),
+		Duration: r.NewHistogram("http_request_duration_seconds", "Request handling latency in seconds, by handler.", DefBuckets, "handler"),
+	}
+}
+
+// Observe records one request for handler that took d.
+func (m *RequestMetrics) Observe(handler string, d time.Duration) {
+	m.Requests.With(handler).Inc()
+	m.Duration.With(handler).Observe(d.Seconds())
+}
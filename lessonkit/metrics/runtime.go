@@ -0,0 +1,105 @@
+package metrics
+
+import (
+	"io"
+	"math"
+	rtmetrics "runtime/metrics"
+	"strings"
+)
+
+// runtimeFamily renders one runtime/metrics value, read fresh on every scrape.
+type runtimeFamily struct {
+	desc rtmetrics.Description
+	prom string
+}
+
+// RegisterRuntimeMetrics registers all runtime/metrics values under a go_ prefix,
+// e.g. /gc/heap/allocs:bytes becomes go_gc_heap_allocs_bytes_total.
+// Names that collide after sanitizing keep the first runtime metric.
+func RegisterRuntimeMetrics(r *Registry) {
+	seen := make(map[string]bool)
+	for _, d := range rtmetrics.All() {
+		prom := RuntimeMetricName(d)
+		if d.Kind == rtmetrics.KindBad || seen[prom] {
+			continue
+		}
+		seen[prom] = true
+		r.register(&runtimeFamily{desc: d, prom: prom})
+	}
+}
+
+// RuntimeMetricName converts a runtime/metrics name to a Prometheus metric name.
+func RuntimeMetricName(d rtmetrics.Description) string {
+	name := strings.TrimPrefix(d.Name, "/")
+	var b strings.Builder
+	b.WriteString("go_")
+	for _, c := range name {
+		if (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9') {
+			b.WriteRune(c)
+		} else {
+			b.WriteByte('_')
+		}
+	}
+	if d.Cumulative && d.Kind != rtmetrics.KindFloat64Histogram {
+		b.WriteString("_total")
+	}
+	return b.String()
+}
+
+func (f *runtimeFamily) name() string { return f.prom }
+
+func (f *runtimeFamily) write(w io.Writer) error {
+	s := []rtmetrics.Sample{{Name: f.desc.Name}}
+	rtmetrics.Read(s)
+
+	typ := "gauge"
+	switch {
+	case f.desc.Kind == rtmetrics.KindFloat64Histogram:
+		typ = "histogram"
+	case f.desc.Cumulative:
+		typ = "counter"
+	}
+	if _, err := io.WriteString(w, "# HELP "+f.prom+" "+escapeHelp(f.desc.Description)+"\n# TYPE "+f.prom+" "+typ+"\n"); err != nil {
+		return err
+	}
+
+	switch s[0].Value.Kind() {
+	case rtmetrics.KindUint64:
+		return writeSample(w, f.prom, "", float64(s[0].Value.Uint64()))
+	case rtmetrics.KindFloat64:
+		return writeSample(w, f.prom, "", s[0].Value.Float64())
+	case rtmetrics.KindFloat64Histogram:
+		upper, counts, sum := coarsenHistogram(s[0].Value.Float64Histogram())
+		return writeHistogram(w, f.prom, "", upper, counts, sum)
+	}
+	return nil
+}
+
+// coarsenHistogram merges runtime histogram buckets so each kept upper bound is
+// at least double the previous one. Runtime histograms have hundreds of fine-grained
+// buckets; log2 buckets keep scrapes small while bucket boundaries stay stable.
+// The sum is estimated from bucket midpoints because the runtime does not track it.
+func coarsenHistogram(h *rtmetrics.Float64Histogram) (upper []float64, counts []uint64, sum float64) {
+	var pending uint64
+	last := 0.0
+	for i, c := range h.Counts {
+		lo, hi := h.Buckets[i], h.Buckets[i+1]
+		if c > 0 && !math.IsInf(lo, 0) && !math.IsInf(hi, 0) {
+			sum += float64(c) * (lo + hi) / 2
+		} else if c > 0 && !math.IsInf(lo, 0) {
+			sum += float64(c) * lo
+		}
+		pending += c
+		if math.IsInf(hi, 1) {
+			break
+		}
+		if len(upper) == 0 || last <= 0 || hi >= 2*last {
+			upper = append(upper, hi)
+			counts = append(counts, pending)
+			pending = 0
+			last = hi
+		}
+	}
+	counts = append(counts, pending)
+	return upper, counts, sum
+}
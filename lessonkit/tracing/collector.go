@@ -0,0 +1,183 @@
+package tracing
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// CollectedSpan is a span as received by Collector.
+type CollectedSpan struct {
+	Service  string
+	TraceID  string
+	SpanID   string
+	ParentID string
+	Name     string
+	Start    time.Time
+	Duration time.Duration
+	Error    string
+}
+
+// Collector is a local stand-in for an OTLP/HTTP collector. It accepts
+// OTLP-JSON POSTs (as sent by HTTPExporter), optionally appends each body to
+// a writer, and keeps the most recent spans so GET can show per-request
+// latency breakdowns.
+type Collector struct {
+	mu    sync.Mutex
+	out   io.Writer
+	spans []CollectedSpan
+	max   int
+}
+
+// NewCollector keeps up to max spans in memory and appends raw batches to
+// out when it is non-nil.
+func NewCollector(out io.Writer, max int) *Collector {
+	if max <= 0 {
+		max = 10000
+	}
+	return &Collector{out: out, max: max}
+}
+
+// ServeHTTP handles POST /v1/traces and GET (a text breakdown of recent traces).
+func (c *Collector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodPost:
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, 16<<20))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := c.Ingest(body); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, "{}")
+	case http.MethodGet:
+		n, err := strconv.Atoi(r.URL.Query().Get("traces"))
+		if err != nil || n <= 0 {
+			n = 20
+		}
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		c.WriteBreakdown(w, n)
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// Ingest decodes one OTLP-JSON request and stores its spans.
+func (c *Collector) Ingest(body []byte) error {
+	var req otlpRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		return fmt.Errorf("decode OTLP-JSON: %w", err)
+	}
+	var got []CollectedSpan
+	for _, rs := range req.ResourceSpans {
+		service := ""
+		for _, kv := range rs.Resource.Attributes {
+			if kv.Key == "service.name" {
+				service = kv.Value.StringValue
+			}
+		}
+		for _, ss := range rs.ScopeSpans {
+			for _, s := range ss.Spans {
+				start, _ := strconv.ParseInt(s.StartTimeUnixNano, 10, 64)
+				end, _ := strconv.ParseInt(s.EndTimeUnixNano, 10, 64)
+				cs := CollectedSpan{
+					Service:  service,
+					TraceID:  s.TraceID,
+					SpanID:   s.SpanID,
+					ParentID: s.ParentSpanID,
+					Name:     s.Name,
+					Start:    time.Unix(0, start),
+					Duration: time.Duration(end - start),
+				}
+				if s.Status != nil {
+					cs.Error = s.Status.Message
+				}
+				got = append(got, cs)
+			}
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.out != nil {
+		c.out.Write(append(body, '\n'))
+	}
+	c.spans = append(c.spans, got...)
+	if over := len(c.spans) - c.max; over > 0 {
+		c.spans = append(c.spans[:0], c.spans[over:]...)
+	}
+	return nil
+}
+
+// Spans returns a copy of the stored spans, oldest first.
+func (c *Collector) Spans() []CollectedSpan {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]CollectedSpan(nil), c.spans...)
+}
+
+// WriteBreakdown prints the last n traces as indented span trees with each
+// span's duration and share of its trace's root.
+func (c *Collector) WriteBreakdown(w io.Writer, n int) {
+	spans := c.Spans()
+	byTrace := map[string][]CollectedSpan{}
+	var order []string
+	for i := len(spans) - 1; i >= 0 && len(order) <= n; i-- {
+		id := spans[i].TraceID
+		if _, ok := byTrace[id]; !ok {
+			order = append(order, id)
+		}
+		byTrace[id] = append(byTrace[id], spans[i])
+	}
+	if len(order) > n {
+		order = order[:n]
+	}
+	for _, id := range order {
+		trace := byTrace[id]
+		sort.Slice(trace, func(i, j int) bool { return trace[i].Start.Before(trace[j].Start) })
+		children := map[string][]CollectedSpan{}
+		present := map[string]bool{}
+		for _, s := range trace {
+			present[s.SpanID] = true
+		}
+		var roots []CollectedSpan
+		for _, s := range trace {
+			if s.ParentID == "" || !present[s.ParentID] {
+				roots = append(roots, s)
+			} else {
+				children[s.ParentID] = append(children[s.ParentID], s)
+			}
+		}
+		fmt.Fprintf(w, "trace %s\n", id)
+		for _, root := range roots {
+			writeSpanTree(w, root, children, root.Duration, 1)
+		}
+	}
+}
+
+func writeSpanTree(w io.Writer, s CollectedSpan, children map[string][]CollectedSpan, total time.Duration, depth int) {
+	pct := 0.0
+	if total > 0 {
+		pct = 100 * float64(s.Duration) / float64(total)
+	}
+	fmt.Fprintf(w, "%*s%-*s %12s %6.1f%%", 2*depth, "", 40-2*depth, s.Name, s.Duration, pct)
+	if s.Service != "" && depth == 1 {
+		fmt.Fprintf(w, "  [%s]", s.Service)
+	}
+	if s.Error != "" {
+		fmt.Fprintf(w, "  error=%q", s.Error)
+	}
+	fmt.Fprintln(w)
+	for _, c := range children[s.SpanID] {
+		writeSpanTree(w, c, children, total, depth+1)
+	}
+}
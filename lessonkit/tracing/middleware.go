@@ -0,0 +1,41 @@
+package tracing
+
+import (
+	"net/http"
+	"strconv"
+
+	"lessonkit/logging"
+)
+
+// Middleware starts a server span per request, joining the caller's trace
+// when a valid traceparent header is present. The trace ID doubles as the
+// request ID (unless the client sent a valid X-Request-ID), so log lines and spans
+// for one request share an identifier. Wrap it outside logging.Middleware:
+//
+//	tracing.Middleware(tracer, logging.Middleware(logger, mux))
+func Middleware(t *Tracer, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		trace, parent, _ := ParseTraceparent(r.Header.Get(TraceparentHeader))
+		ctx, span := t.StartRoot(r.Context(), r.Method+" "+r.URL.Path, trace, parent)
+		span.SetAttr("http.method", r.Method)
+		span.SetAttr("url.path", r.URL.Path)
+
+		id := logging.IncomingRequestID(r)
+		if id == "" {
+			id = span.TraceID.String()
+		}
+		ctx = logging.WithRequestID(ctx, id)
+		w.Header().Set(TraceparentHeader, FormatTraceparent(span.TraceID, span.SpanID))
+
+		rec := &logging.StatusRecorder{ResponseWriter: w}
+		next.ServeHTTP(rec, r.WithContext(ctx))
+		if rec.Status == 0 {
+			rec.Status = http.StatusOK
+		}
+		span.SetAttr("http.status_code", strconv.Itoa(rec.Status))
+		if rec.Status >= 500 {
+			span.SetError(http.StatusText(rec.Status))
+		}
+		span.End()
+	})
+}
@@ -0,0 +1,210 @@
+package tracing
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// The types below are the subset of the OTLP/HTTP JSON encoding
+// (ExportTraceServiceRequest) the servers emit. Per the OTLP spec, trace and
+// span IDs are hex strings and 64-bit timestamps are decimal strings.
+
+type otlpRequest struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+type otlpResourceSpans struct {
+	Resource   otlpResource     `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpResource struct {
+	Attributes []otlpKeyValue `json:"attributes"`
+}
+
+type otlpScopeSpans struct {
+	Scope otlpScope  `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpScope struct {
+	Name string `json:"name"`
+}
+
+type otlpSpan struct {
+	TraceID           string         `json:"traceId"`
+	SpanID            string         `json:"spanId"`
+	ParentSpanID      string         `json:"parentSpanId,omitempty"`
+	Name              string         `json:"name"`
+	Kind              int            `json:"kind"`
+	StartTimeUnixNano string         `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string         `json:"endTimeUnixNano"`
+	Attributes        []otlpKeyValue `json:"attributes,omitempty"`
+	Status            *otlpStatus    `json:"status,omitempty"`
+}
+
+type otlpKeyValue struct {
+	Key   string    `json:"key"`
+	Value otlpValue `json:"value"`
+}
+
+type otlpValue struct {
+	StringValue string `json:"stringValue"`
+}
+
+type otlpStatus struct {
+	Code    int    `json:"code"` // 2 = STATUS_CODE_ERROR
+	Message string `json:"message,omitempty"`
+}
+
+const scopeName = "lessonkit/tracing"
+
+// EncodeOTLP renders spans as one OTLP-JSON ExportTraceServiceRequest.
+// Spans are grouped by the service of the tracer that created them.
+func EncodeOTLP(spans []*Span) ([]byte, error) {
+	var req otlpRequest
+	index := map[string]int{}
+	for _, s := range spans {
+		service := ""
+		if s.tracer != nil {
+			service = s.tracer.Service
+		}
+		i, ok := index[service]
+		if !ok {
+			i = len(req.ResourceSpans)
+			index[service] = i
+			req.ResourceSpans = append(req.ResourceSpans, otlpResourceSpans{
+				Resource: otlpResource{Attributes: []otlpKeyValue{
+					{Key: "service.name", Value: otlpValue{service}},
+				}},
+				ScopeSpans: []otlpScopeSpans{{Scope: otlpScope{scopeName}}},
+			})
+		}
+		ss := &req.ResourceSpans[i].ScopeSpans[0]
+		ss.Spans = append(ss.Spans, toOTLP(s))
+	}
+	return json.Marshal(req)
+}
+
+func toOTLP(s *Span) otlpSpan {
+	o := otlpSpan{
+		TraceID:           s.TraceID.String(),
+		SpanID:            s.SpanID.String(),
+		Name:              s.Name,
+		Kind:              int(s.Kind),
+		StartTimeUnixNano: strconv.FormatInt(s.Start.UnixNano(), 10),
+		EndTimeUnixNano:   strconv.FormatInt(s.EndTime.UnixNano(), 10),
+	}
+	if s.ParentID.IsValid() {
+		o.ParentSpanID = s.ParentID.String()
+	}
+	for _, a := range s.Attrs {
+		o.Attributes = append(o.Attributes, otlpKeyValue{Key: a.Key, Value: otlpValue{a.Value}})
+	}
+	if s.Error != "" {
+		o.Status = &otlpStatus{Code: 2, Message: s.Error}
+	}
+	return o
+}
+
+// FileExporter appends one OTLP-JSON request per batch, newline-delimited,
+// which is the format the OpenTelemetry Collector's file exporter writes.
+type FileExporter struct {
+	mu sync.Mutex
+	w  io.Writer
+	c  io.Closer
+}
+
+// NewFileExporter writes batches to w. If w is an io.Closer it is closed
+// when the tracer closes.
+func NewFileExporter(w io.Writer) *FileExporter {
+	e := &FileExporter{w: w}
+	if c, ok := w.(io.Closer); ok {
+		e.c = c
+	}
+	return e
+}
+
+func (e *FileExporter) Export(spans []*Span) error {
+	b, err := EncodeOTLP(spans)
+	if err != nil {
+		return err
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	_, err = e.w.Write(append(b, '\n'))
+	return err
+}
+
+func (e *FileExporter) Close() error {
+	if e.c == nil {
+		return nil
+	}
+	return e.c.Close()
+}
+
+// HTTPExporter POSTs batches to an OTLP/HTTP endpoint such as
+// http://localhost:4318/v1/traces.
+type HTTPExporter struct {
+	URL    string
+	Client *http.Client
+}
+
+// NewHTTPExporter posts to endpoint with a short timeout so a missing collector
+// only costs the background goroutine, never a request.
+func NewHTTPExporter(endpoint string) *HTTPExporter {
+	return &HTTPExporter{URL: endpoint, Client: &http.Client{Timeout: 5 * time.Second}}
+}
+
+func (e *HTTPExporter) Export(spans []*Span) error {
+	b, err := EncodeOTLP(spans)
+	if err != nil {
+		return err
+	}
+	resp, err := e.Client.Post(e.URL, "application/json", bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("otlp export: %s", resp.Status)
+	}
+	return nil
+}
+
+// FromEnv builds a tracer for service from TRACE_EXPORT. A value starting
+// with http:// or https:// is an OTLP/HTTP endpoint ("/v1/traces" is
+// appended when no path is given); any other value is a file path to append
+// OTLP-JSON to. Empty disables export while still propagating IDs.
+func FromEnv(service string) (*Tracer, error) {
+	target := os.Getenv("TRACE_EXPORT")
+	switch {
+	case target == "":
+		return NewTracer(service, nil), nil
+	case strings.HasPrefix(target, "http://"), strings.HasPrefix(target, "https://"):
+		u, err := url.Parse(target)
+		if err != nil {
+			return nil, fmt.Errorf("parse TRACE_EXPORT: %w", err)
+		}
+		if u.Path == "" || u.Path == "/" {
+			u.Path = "/v1/traces"
+		}
+		return NewTracer(service, NewHTTPExporter(u.String())), nil
+	default:
+		f, err := os.OpenFile(target, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+		if err != nil {
+			return nil, fmt.Errorf("open trace file: %w", err)
+		}
+		return NewTracer(service, NewFileExporter(f)), nil
+	}
+}
@@ -0,0 +1,309 @@
+// Package tracing gives the lesson servers minimal request tracing: W3C
+// traceparent propagation, spans for handler phases, and OTLP-JSON export
+// to a file or a collector endpoint. It has no dependencies outside the
+// standard library, so latency breakdowns can be inspected without an
+// OpenTelemetry SDK.
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// TraceID identifies a whole request across services.
+type TraceID [16]byte
+
+// SpanID identifies one span within a trace.
+type SpanID [8]byte
+
+func (t TraceID) String() string { return hex.EncodeToString(t[:]) }
+func (s SpanID) String() string  { return hex.EncodeToString(s[:]) }
+
+// IsValid reports whether t is non-zero, as the W3C spec requires.
+func (t TraceID) IsValid() bool { return t != TraceID{} }
+
+// IsValid reports whether s is non-zero.
+func (s SpanID) IsValid() bool { return s != SpanID{} }
+
+func newTraceID() TraceID {
+	var t TraceID
+	for !t.IsValid() {
+		rand.Read(t[:])
+	}
+	return t
+}
+
+func newSpanID() SpanID {
+	var s SpanID
+	for !s.IsValid() {
+		rand.Read(s[:])
+	}
+	return s
+}
+
+// TraceparentHeader is the W3C Trace Context propagation header.
+const TraceparentHeader = "traceparent"
+
+// ParseTraceparent parses a version-00 traceparent header
+// ("00-<32 hex trace id>-<16 hex parent id>-<2 hex flags>").
+func ParseTraceparent(h string) (TraceID, SpanID, bool) {
+	var t TraceID
+	var s SpanID
+	parts := strings.Split(strings.TrimSpace(h), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" ||
+		len(parts[1]) != 32 || len(parts[2]) != 16 || len(parts[3]) != 2 {
+		return t, s, false
+	}
+	if parts[0] == "00" && len(parts) != 4 {
+		return t, s, false
+	}
+	if _, err := hex.Decode(t[:], []byte(parts[1])); err != nil {
+		return t, s, false
+	}
+	if _, err := hex.Decode(s[:], []byte(parts[2])); err != nil {
+		return t, s, false
+	}
+	if !t.IsValid() || !s.IsValid() {
+		return t, s, false
+	}
+	return t, s, true
+}
+
+// FormatTraceparent returns the traceparent header value for a sampled span.
+func FormatTraceparent(t TraceID, s SpanID) string {
+	return fmt.Sprintf("00-%s-%s-01", t, s)
+}
+
+// SpanKind mirrors the OTLP span kinds the servers use.
+type SpanKind int
+
+const (
+	KindInternal SpanKind = 1
+	KindServer   SpanKind = 2
+)
+
+// Attr is a string-valued span attribute. Numeric values are formatted by
+// the caller; OTLP-JSON consumers accept them as strings.
+type Attr struct {
+	Key   string
+	Value string
+}
+
+// Span is one timed operation. A nil *Span is a valid no-op, so handlers can
+// call Start and End unconditionally even when no tracer is installed.
+type Span struct {
+	tracer *Tracer
+
+	TraceID  TraceID
+	SpanID   SpanID
+	ParentID SpanID
+	Name     string
+	Kind     SpanKind
+	Start    time.Time
+	EndTime  time.Time
+	Attrs    []Attr
+	Error    string
+
+	ended atomic.Bool
+}
+
+// SetAttr records a key/value pair on the span.
+func (s *Span) SetAttr(key, value string) {
+	if s == nil {
+		return
+	}
+	s.Attrs = append(s.Attrs, Attr{key, value})
+}
+
+// SetError marks the span as failed with msg.
+func (s *Span) SetError(msg string) {
+	if s == nil {
+		return
+	}
+	s.Error = msg
+}
+
+// End records the end time and hands the span to its tracer's exporter.
+// Calls after the first are ignored.
+func (s *Span) End() {
+	if s == nil || !s.ended.CompareAndSwap(false, true) {
+		return
+	}
+	s.EndTime = time.Now()
+	s.tracer.enqueue(s)
+}
+
+// Duration is the span's length, or zero if it has not ended.
+func (s *Span) Duration() time.Duration {
+	if s == nil || s.EndTime.IsZero() {
+		return 0
+	}
+	return s.EndTime.Sub(s.Start)
+}
+
+type spanKey struct{}
+
+// ContextWithSpan returns a context carrying s as the current span.
+func ContextWithSpan(ctx context.Context, s *Span) context.Context {
+	return context.WithValue(ctx, spanKey{}, s)
+}
+
+// SpanFromContext returns the current span, or nil.
+func SpanFromContext(ctx context.Context) *Span {
+	s, _ := ctx.Value(spanKey{}).(*Span)
+	return s
+}
+
+// Start begins a child of the span in ctx. Without a current span it returns
+// ctx unchanged and a nil (no-op) span.
+func Start(ctx context.Context, name string) (context.Context, *Span) {
+	parent := SpanFromContext(ctx)
+	if parent == nil {
+		return ctx, nil
+	}
+	s := &Span{
+		tracer:   parent.tracer,
+		TraceID:  parent.TraceID,
+		SpanID:   newSpanID(),
+		ParentID: parent.SpanID,
+		Name:     name,
+		Kind:     KindInternal,
+		Start:    time.Now(),
+	}
+	return ContextWithSpan(ctx, s), s
+}
+
+// Exporter receives finished spans in batches.
+type Exporter interface {
+	Export(spans []*Span) error
+}
+
+// Tracer creates root spans and batches finished spans for an Exporter on a
+// background goroutine. Like logging.AsyncWriter, a full queue drops spans
+// rather than blocking request handling.
+type Tracer struct {
+	Service string
+
+	exp      Exporter
+	spans    chan *Span
+	done     chan struct{}
+	dropped  atomic.Uint64
+	failed   atomic.Uint64
+	batch    int
+	interval time.Duration
+
+	mu     sync.RWMutex // guards closed against concurrent enqueue/Close
+	closed bool
+
+	closeOnce sync.Once // the exporter is closed exactly once
+	closeErr  error
+}
+
+// NewTracer starts a tracer for service. A nil exporter discards spans but
+// still propagates IDs, which is the default when tracing is not configured.
+func NewTracer(service string, exp Exporter) *Tracer {
+	t := &Tracer{
+		Service:  service,
+		exp:      exp,
+		spans:    make(chan *Span, 4096),
+		done:     make(chan struct{}),
+		batch:    256,
+		interval: time.Second,
+	}
+	go t.run()
+	return t
+}
+
+// StartRoot begins a server span. When parent is valid the span joins that
+// trace; otherwise a new trace is started.
+func (t *Tracer) StartRoot(ctx context.Context, name string, trace TraceID, parent SpanID) (context.Context, *Span) {
+	if !trace.IsValid() {
+		trace, parent = newTraceID(), SpanID{}
+	}
+	s := &Span{
+		tracer:   t,
+		TraceID:  trace,
+		SpanID:   newSpanID(),
+		ParentID: parent,
+		Name:     name,
+		Kind:     KindServer,
+		Start:    time.Now(),
+	}
+	return ContextWithSpan(ctx, s), s
+}
+
+func (t *Tracer) enqueue(s *Span) {
+	if t == nil || t.exp == nil {
+		return
+	}
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	if t.closed {
+		t.dropped.Add(1)
+		return
+	}
+	select {
+	case t.spans <- s:
+	default:
+		t.dropped.Add(1)
+	}
+}
+
+func (t *Tracer) run() {
+	defer close(t.done)
+	tick := time.NewTicker(t.interval)
+	defer tick.Stop()
+	var pending []*Span
+	flush := func() {
+		if len(pending) == 0 {
+			return
+		}
+		if err := t.exp.Export(pending); err != nil {
+			t.failed.Add(uint64(len(pending)))
+		}
+		pending = nil
+	}
+	for {
+		select {
+		case s, ok := <-t.spans:
+			if !ok {
+				flush()
+				return
+			}
+			pending = append(pending, s)
+			if len(pending) >= t.batch {
+				flush()
+			}
+		case <-tick.C:
+			flush()
+		}
+	}
+}
+
+// Dropped returns how many spans were discarded because the queue was full.
+func (t *Tracer) Dropped() uint64 { return t.dropped.Load() }
+
+// Failed returns how many spans the exporter failed to deliver.
+func (t *Tracer) Failed() uint64 { return t.failed.Load() }
+
+// Close stops accepting spans and waits until queued spans are exported.
+// It is safe to call more than once; later calls return the first result.
+func (t *Tracer) Close() error {
+	t.closeOnce.Do(func() {
+		t.mu.Lock()
+		t.closed = true
+		close(t.spans)
+		t.mu.Unlock()
+		<-t.done
+		if c, ok := t.exp.(interface{ Close() error }); ok {
+			t.closeErr = c.Close()
+		}
+	})
+	return t.closeErr
+}
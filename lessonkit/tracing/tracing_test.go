@@ -0,0 +1,189 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"lessonkit/logging"
+)
+
+// memExporter collects exported spans for inspection.
+type memExporter struct {
+	mu    sync.Mutex
+	spans []*Span
+}
+
+func (m *memExporter) Export(spans []*Span) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.spans = append(m.spans, spans...)
+	return nil
+}
+
+func TestParseTraceparent(t *testing.T) {
+	const h = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	trace, parent, ok := ParseTraceparent(h)
+	if !ok {
+		t.Fatalf("ParseTraceparent(%q) failed", h)
+	}
+	if trace.String() != "4bf92f3577b34da6a3ce929d0e0e4736" || parent.String() != "00f067aa0ba902b7" {
+		t.Errorf("got trace %s parent %s", trace, parent)
+	}
+	if got := FormatTraceparent(trace, parent); got != h {
+		t.Errorf("FormatTraceparent = %q, want %q", got, h)
+	}
+
+	for _, bad := range []string{
+		"",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01",
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e473-00f067aa0ba902b7-01",
+		"00-zzf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+	} {
+		if _, _, ok := ParseTraceparent(bad); ok {
+			t.Errorf("ParseTraceparent(%q) accepted invalid header", bad)
+		}
+	}
+}
+
+func TestMiddlewarePropagatesTraceAndRequestID(t *testing.T) {
+	exp := &memExporter{}
+	tracer := NewTracer("test", exp)
+
+	var gotID string
+	h := Middleware(tracer, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotID = logging.RequestID(r.Context())
+		_, span := Start(r.Context(), "work")
+		span.End()
+	}))
+
+	req := httptest.NewRequest("GET", "/x", nil)
+	req.Header.Set(TraceparentHeader, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	firstID := gotID
+
+	// An unrelated request must get a fresh trace.
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/y", nil))
+	tracer.Close()
+
+	if firstID != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("request ID = %q, want the incoming trace ID", firstID)
+	}
+	trace, _, ok := ParseTraceparent(rec.Header().Get(TraceparentHeader))
+	if !ok || trace.String() != firstID {
+		t.Errorf("response traceparent = %q", rec.Header().Get(TraceparentHeader))
+	}
+
+	if len(exp.spans) != 4 {
+		t.Fatalf("exported %d spans, want 4", len(exp.spans))
+	}
+	work, root := exp.spans[0], exp.spans[1]
+	if root.ParentID.String() != "00f067aa0ba902b7" {
+		t.Errorf("root parent = %s, want incoming parent", root.ParentID)
+	}
+	if work.ParentID != root.SpanID || work.TraceID != root.TraceID {
+		t.Errorf("child span not linked to root: %+v", work)
+	}
+	if exp.spans[3].TraceID == root.TraceID {
+		t.Error("second request reused the first request's trace ID")
+	}
+}
+
+func TestMiddlewareReplacesInvalidRequestID(t *testing.T) {
+	tracer := NewTracer("test", nil)
+	defer tracer.Close()
+	var gotID string
+	h := Middleware(tracer, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotID = logging.RequestID(r.Context())
+	}))
+	req := httptest.NewRequest("GET", "/x", nil)
+	req.Header.Set(logging.RequestIDHeader, "has spaces\tand tabs")
+	h.ServeHTTP(httptest.NewRecorder(), req)
+	if !logging.ValidRequestID(gotID) || len(gotID) != 32 {
+		t.Errorf("request ID = %q, want the generated trace ID", gotID)
+	}
+}
+
+type closeCountingExporter struct {
+	memExporter
+	closes atomic.Int32
+}
+
+func (c *closeCountingExporter) Close() error {
+	c.closes.Add(1)
+	return nil
+}
+
+func TestTracerCloseIsIdempotent(t *testing.T) {
+	exp := &closeCountingExporter{}
+	tracer := NewTracer("test", exp)
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			tracer.Close()
+		}()
+	}
+	wg.Wait()
+	tracer.Close()
+	if n := exp.closes.Load(); n != 1 {
+		t.Errorf("exporter closed %d times, want 1", n)
+	}
+}
+
+func TestStartWithoutSpanIsNoop(t *testing.T) {
+	ctx, span := Start(context.Background(), "orphan")
+	span.SetAttr("k", "v")
+	span.End()
+	if span != nil || SpanFromContext(ctx) != nil {
+		t.Error("Start without a parent span should return a nil span")
+	}
+}
+
+func TestOTLPRoundTripThroughCollector(t *testing.T) {
+	var raw bytes.Buffer
+	c := NewCollector(&raw, 100)
+	srv := httptest.NewServer(c)
+	defer srv.Close()
+
+	tracer := NewTracer("lesson", NewHTTPExporter(srv.URL+"/v1/traces"))
+	ctx, root := tracer.StartRoot(context.Background(), "GET /stats", TraceID{}, SpanID{})
+	_, child := Start(ctx, "process")
+	child.SetError("boom")
+	child.End()
+	root.End()
+	tracer.Close()
+
+	if tracer.Failed() != 0 {
+		t.Fatalf("export failed for %d spans", tracer.Failed())
+	}
+	var req map[string]any
+	if err := json.Unmarshal(bytes.TrimSpace(raw.Bytes()), &req); err != nil {
+		t.Fatalf("collector stored invalid JSON: %v", err)
+	}
+	spans := c.Spans()
+	if len(spans) != 2 || spans[0].Service != "lesson" || spans[0].Error != "boom" {
+		t.Fatalf("collected spans = %+v", spans)
+	}
+
+	resp, err := http.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var out bytes.Buffer
+	out.ReadFrom(resp.Body)
+	if !strings.Contains(out.String(), "trace "+root.TraceID.String()) || !strings.Contains(out.String(), "    process") {
+		t.Errorf("breakdown missing trace tree:\n%s", out.String())
+	}
+}